@@ -1,7 +1,9 @@
 package main
 
 import (
+	"RBKproject4/internal/logging"
 	"RBKproject4/internal/server"
+	"RBKproject4/internal/tracing"
 	"RBKproject4/pkg/config"
 	"context"
 	"errors"
@@ -16,15 +18,38 @@ import (
 )
 
 func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	redactor, err := logging.NewRedactor(logging.RedactRules{
+		Fields:   cfg.LogRedactFields,
+		Patterns: cfg.LogRedactPatterns,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the request ID is added after redaction, so that it is never masked
+	logger := slog.New(logging.NewRedactHandler(logging.NewContextHandler(slog.NewTextHandler(os.Stdout, nil)), redactor))
+	slog.SetDefault(logger)
 	cfg.StaticToken = strings.TrimSpace(cfg.StaticToken)
 
-	httpServer := server.NewServer(cfg, logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	httpServer, err := server.NewServer(cfg, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -48,6 +73,9 @@ func main() {
 		logger.Error("Server forced to shutdown", "error", err)
 		os.Exit(1)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
 
 	logger.Info("Server exited")
 }
@@ -0,0 +1,154 @@
+// Package audit keeps an append-only, hash-chained record of every document
+// the service generates, so that we can prove what was produced, for whom and
+// when.
+package audit
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// Sink stores audit records. Records are appended in chain order and must
+// be scanned back in the same order.
+type Sink interface {
+	Append(ctx context.Context, rec *models.AuditRecord) error
+	// Scan calls fn with every record in order until fn returns false.
+	Scan(ctx context.Context, fn func(*models.AuditRecord) bool) error
+}
+
+// Log chains records and writes them to a sink. A nil *Log records nothing.
+type Log struct {
+	sink Sink
+
+	mu       sync.Mutex
+	seq      uint64
+	lastHash string
+}
+
+// NewLog continues the chain already in sink.
+func NewLog(ctx context.Context, sink Sink) (*Log, error) {
+	l := &Log{sink: sink}
+	err := sink.Scan(ctx, func(rec *models.AuditRecord) bool {
+		l.seq, l.lastHash = rec.Seq, rec.Hash
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return l, nil
+}
+
+// Record sets the sequence number, time and hashes of rec and appends it.
+func (l *Log) Record(ctx context.Context, rec *models.AuditRecord) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec.Seq = l.seq + 1
+	rec.Time = time.Now().UTC()
+	rec.PrevHash = l.lastHash
+	rec.Hash = Hash(rec)
+	if err := l.sink.Append(ctx, rec); err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+	l.seq, l.lastHash = rec.Seq, rec.Hash
+	return nil
+}
+
+// Query returns the records matching q in chain order, at most q.Limit of
+// them.
+func (l *Log) Query(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	limit = min(limit, MaxQueryLimit)
+
+	page := &models.AuditPage{Records: []models.AuditRecord{}}
+	err := l.sink.Scan(ctx, func(rec *models.AuditRecord) bool {
+		if !matches(rec, q) {
+			return true
+		}
+		if len(page.Records) == limit {
+			page.Next = page.Records[limit-1].Seq
+			return false
+		}
+		page.Records = append(page.Records, *rec)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return page, nil
+}
+
+func matches(rec *models.AuditRecord, q models.AuditQuery) bool {
+	return rec.Seq > q.After &&
+		(q.Code == "" || rec.Code == q.Code) &&
+		(q.ClientID == "" || rec.ClientID == q.ClientID) &&
+		(q.From.IsZero() || !rec.Time.Before(q.From)) &&
+		(q.To.IsZero() || rec.Time.Before(q.To))
+}
+
+// Verify checks the whole chain: every record must hash to its Hash, point
+// at the one before it and follow it in sequence.
+func (l *Log) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true}
+	var prev *models.AuditRecord
+	err := l.sink.Scan(ctx, func(rec *models.AuditRecord) bool {
+		var problem string
+		switch {
+		case prev == nil && (rec.Seq != 1 || rec.PrevHash != ""):
+			problem = "chain doesn't start at the first record"
+		case prev != nil && rec.Seq != prev.Seq+1:
+			problem = fmt.Sprintf("record follows record %d", prev.Seq)
+		case prev != nil && rec.PrevHash != prev.Hash:
+			problem = "previous hash doesn't match"
+		case Hash(rec) != rec.Hash:
+			problem = "hash doesn't match the record"
+		}
+		if problem != "" {
+			result.Valid = false
+			result.BrokenAt = rec.Seq
+			result.Error = problem
+			return false
+		}
+		result.Records++
+		prev = rec
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return result, nil
+}
+
+// Hash is the SHA-256 of rec with its Hash left empty.
+func Hash(rec *models.AuditRecord) string {
+	r := *rec
+	r.Hash = ""
+	// a struct of strings, numbers and a time always marshals
+	data, _ := json.Marshal(&r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA256 is the hex SHA-256 of data, as records carry it.
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,190 @@
+package audit_test
+
+import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/models"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newLog(t *testing.T, path string) (*audit.Log, *audit.FileSink) {
+	t.Helper()
+	sink, err := audit.NewFileSink(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	log, err := audit.NewLog(context.Background(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log, sink
+}
+
+func record(t *testing.T, log *audit.Log, code, client string) *models.AuditRecord {
+	t.Helper()
+	rec := &models.AuditRecord{Code: code, ClientID: client, Format: "pdf", OutputSHA256: audit.SHA256([]byte(code)), Size: len(code)}
+	if err := log.Record(context.Background(), rec); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	return rec
+}
+
+func TestLog_Query(t *testing.T) {
+	ctx := context.Background()
+	log, _ := newLog(t, filepath.Join(t.TempDir(), "audit.jsonl"))
+
+	first := record(t, log, "PDP", "mobile")
+	record(t, log, "CARD_STATEMENT", "mobile")
+	record(t, log, "PDP", "web")
+	record(t, log, "PDP", "mobile")
+
+	if first.Seq != 1 || first.PrevHash != "" || first.Hash != audit.Hash(first) {
+		t.Errorf("first record = %+v, want seq 1 starting the chain", first)
+	}
+
+	tests := []struct {
+		name     string
+		query    models.AuditQuery
+		wantSeqs []uint64
+		wantNext uint64
+	}{
+		{"all", models.AuditQuery{}, []uint64{1, 2, 3, 4}, 0},
+		{"code", models.AuditQuery{Code: "PDP"}, []uint64{1, 3, 4}, 0},
+		{"client", models.AuditQuery{ClientID: "mobile"}, []uint64{1, 2, 4}, 0},
+		{"code and client", models.AuditQuery{Code: "PDP", ClientID: "mobile"}, []uint64{1, 4}, 0},
+		{"page", models.AuditQuery{Code: "PDP", Limit: 2}, []uint64{1, 3}, 3},
+		{"next page", models.AuditQuery{Code: "PDP", Limit: 2, After: 3}, []uint64{4}, 0},
+		{"from", models.AuditQuery{From: first.Time.Add(time.Hour)}, nil, 0},
+		{"to", models.AuditQuery{To: first.Time}, nil, 0},
+		{"range", models.AuditQuery{From: first.Time, To: time.Now().Add(time.Minute)}, []uint64{1, 2, 3, 4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := log.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error: %v", err)
+			}
+			var seqs []uint64
+			for _, rec := range page.Records {
+				seqs = append(seqs, rec.Seq)
+			}
+			if len(seqs) != len(tt.wantSeqs) {
+				t.Fatalf("records = %v, want %v", seqs, tt.wantSeqs)
+			}
+			for i := range seqs {
+				if seqs[i] != tt.wantSeqs[i] {
+					t.Fatalf("records = %v, want %v", seqs, tt.wantSeqs)
+				}
+			}
+			if page.Next != tt.wantNext {
+				t.Errorf("next = %d, want %d", page.Next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestLog_ContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, sink := newLog(t, path)
+	last := record(t, log, "PDP", "mobile")
+	sink.Close()
+
+	// as after a restart
+	log, _ = newLog(t, path)
+	next := record(t, log, "PDP", "mobile")
+	if next.Seq != 2 || next.PrevHash != last.Hash {
+		t.Errorf("record after reopening = %+v, want seq 2 chained to %s", next, last.Hash)
+	}
+
+	result, err := log.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if !result.Valid || result.Records != 2 {
+		t.Errorf("Verify() = %+v, want 2 valid records", result)
+	}
+}
+
+func TestLog_VerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(lines [][]byte) [][]byte
+		wantBrokenAt uint64
+	}{
+		{"changed", func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"clientId":"mobile"`), []byte(`"clientId":"web"`), 1)
+			return lines
+		}, 2},
+		{"removed", func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, 3},
+		{"reordered", func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, 3},
+		{"first removed", func(lines [][]byte) [][]byte {
+			return lines[1:]
+		}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			log, _ := newLog(t, path)
+			for range 3 {
+				record(t, log, "PDP", "mobile")
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+			if err := os.WriteFile(path, append(bytes.Join(tt.tamper(lines), []byte("\n")), '\n'), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := log.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() error: %v", err)
+			}
+			if result.Valid || result.BrokenAt != tt.wantBrokenAt {
+				t.Errorf("Verify() = %+v, want broken at %d", result, tt.wantBrokenAt)
+			}
+		})
+	}
+}
+
+func TestLog_RepairsTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, sink := newLog(t, path)
+	last := record(t, log, "PDP", "mobile")
+	sink.Close()
+
+	// as after a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"code":"PD`)
+	f.Close()
+
+	log, _ = newLog(t, path)
+	next := record(t, log, "PDP", "mobile")
+	if next.Seq != 2 || next.PrevHash != last.Hash {
+		t.Errorf("record after the torn one = %+v, want seq 2 chained to %s", next, last.Hash)
+	}
+
+	result, err := log.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if !result.Valid || result.Records != 2 {
+		t.Errorf("Verify() = %+v, want 2 valid records", result)
+	}
+}
@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"RBKproject4/internal/models"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink appends records to a file as JSON lines. The file is only ever
+// appended to, and every record is synced before Append returns.
+type FileSink struct {
+	path   string
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	// size is the length of the records appended so far, which a failed
+	// Append truncates back to
+	size int64
+	// broken is set once a failed Append could not be undone
+	broken error
+}
+
+// NewFileSink opens the log at path. A record left half written by a crash
+// is cut off, as nothing was acknowledged for it.
+func NewFileSink(logger *slog.Logger, path string) (*FileSink, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	s := &FileSink{path: path, logger: logger, file: f}
+	if err := s.repair(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// repair truncates whatever follows the last complete line.
+func (s *FileSink) repair() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	end, err := lastLineEnd(s.path, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if end < info.Size() {
+		s.logger.Warn("truncating torn audit record", "path", s.path, "offset", end, "bytes", info.Size()-end)
+		if err := s.file.Truncate(end); err != nil {
+			return fmt.Errorf("failed to truncate torn audit record: %w", err)
+		}
+	}
+	s.size = end
+	return nil
+}
+
+// lastLineEnd returns the offset just past the last newline in the first
+// size bytes of the file at path.
+func lastLineEnd(path string, size int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+func (s *FileSink) Append(_ context.Context, rec *models.AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken != nil {
+		return s.broken
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return s.rollback(err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return s.rollback(err)
+	}
+	s.size += int64(len(line))
+	return nil
+}
+
+// rollback removes what a failed Append may have written, so that the record
+// is either in the log and acknowledged or in neither. If that fails too, the
+// sink refuses further records rather than chain them after an unknown one.
+func (s *FileSink) rollback(err error) error {
+	if terr := s.file.Truncate(s.size); terr != nil {
+		s.broken = fmt.Errorf("audit log %s is in an unknown state: %w", s.path, terr)
+		return errors.Join(err, s.broken)
+	}
+	return err
+}
+
+func (s *FileSink) Scan(ctx context.Context, fn func(*models.AuditRecord) bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a last line without its newline is still being written, or
+			// was torn after NewFileSink repaired the file
+			if len(bytes.TrimSpace(line)) > 0 {
+				s.logger.WarnContext(ctx, "skipping incomplete audit record", "path", s.path, "line", n)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var rec models.AuditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("corrupt audit record on line %d: %w", n, err)
+		}
+		if !fn(&rec) {
+			return nil
+		}
+	}
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,178 @@
+// Package clients holds the API clients allowed to call the service: their
+// tokens, kept only as hashes, and the templates and formats each may use.
+package clients
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultClientID identifies callers authenticated with the static token.
+const DefaultClientID = "default"
+
+const tokenHashPrefix = "sha256:"
+
+// Client is one API client of the registry file.
+type Client struct {
+	ID      string `yaml:"id"`
+	Enabled bool   `yaml:"enabled"`
+	// Admin grants the admin endpoints, e.g. the audit log.
+	Admin bool `yaml:"admin"`
+	// Tokens are "sha256:" followed by the hex SHA-256 of a token.
+	Tokens []string `yaml:"tokens"`
+	// Templates are template codes or glob patterns such as "CARD_*".
+	Templates []string `yaml:"templates"`
+	// Formats are output formats, "*" for all of them.
+	Formats []string `yaml:"formats"`
+
+	hashes [][]byte
+}
+
+// AllowsTemplate reports whether the client may use the template code.
+func (c *Client) AllowsTemplate(code string) bool {
+	for _, pattern := range c.Templates {
+		if ok, _ := path.Match(pattern, code); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsFormat reports whether the client may generate documents in format.
+func (c *Client) AllowsFormat(format string) bool {
+	for _, f := range c.Formats {
+		if f == "*" || strings.EqualFold(f, format) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry is the set of clients.
+type Registry struct {
+	clients []*Client
+	byID    map[string]*Client
+}
+
+type registryFile struct {
+	Clients []*Client `yaml:"clients"`
+}
+
+// Load reads the registry from a YAML file.
+func Load(file string) (*Registry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clients file: %w", err)
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clients file %s: %w", file, err)
+	}
+	return r, nil
+}
+
+func Parse(data []byte) (*Registry, error) {
+	var f registryFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	r := &Registry{byID: map[string]*Client{}}
+	seen := map[string]string{}
+	for i, c := range f.Clients {
+		if c.ID == "" {
+			return nil, fmt.Errorf("client %d has no id", i+1)
+		}
+		if _, ok := r.byID[c.ID]; ok {
+			return nil, fmt.Errorf("client %q is listed twice", c.ID)
+		}
+		for _, pattern := range c.Templates {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("client %q: invalid template pattern %q", c.ID, pattern)
+			}
+		}
+		for _, token := range c.Tokens {
+			hash, err := parseTokenHash(token)
+			if err != nil {
+				return nil, fmt.Errorf("client %q: %w", c.ID, err)
+			}
+			if other, ok := seen[string(hash)]; ok {
+				return nil, fmt.Errorf("client %q shares a token with client %q", c.ID, other)
+			}
+			seen[string(hash)] = c.ID
+			c.hashes = append(c.hashes, hash)
+		}
+		r.clients = append(r.clients, c)
+		r.byID[c.ID] = c
+	}
+	return r, nil
+}
+
+func parseTokenHash(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, tokenHashPrefix) {
+		return nil, errors.New(`token hashes must start with "sha256:"`)
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(s, tokenHashPrefix))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, errors.New("token hash is not a hex SHA-256")
+	}
+	return hash, nil
+}
+
+// Static is a registry of a single enabled admin client, DefaultClientID,
+// that may use every template and format with token, for deployments
+// without a clients file.
+func Static(token string) *Registry {
+	hash := sha256.Sum256([]byte(token))
+	c := &Client{
+		ID:        DefaultClientID,
+		Enabled:   true,
+		Admin:     true,
+		Templates: []string{"*"},
+		Formats:   []string{"*"},
+		hashes:    [][]byte{hash[:]},
+	}
+	return &Registry{clients: []*Client{c}, byID: map[string]*Client{c.ID: c}}
+}
+
+// HashToken returns the form a token is stored in in the clients file.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return tokenHashPrefix + hex.EncodeToString(hash[:])
+}
+
+// Authenticate finds the enabled client token belongs to. Every hash is
+// compared in constant time, and all of them always are, so that the time
+// taken tells nothing about the tokens.
+func (r *Registry) Authenticate(token string) (*Client, bool) {
+	hash := sha256.Sum256([]byte(token))
+	var found *Client
+	for _, c := range r.clients {
+		for _, h := range c.hashes {
+			if subtle.ConstantTimeCompare(hash[:], h) == 1 {
+				found = c
+			}
+		}
+	}
+	if found == nil || !found.Enabled {
+		return nil, false
+	}
+	return found, true
+}
+
+// Client returns the client with id, if it is enabled.
+func (r *Registry) Client(id string) (*Client, bool) {
+	c, ok := r.byID[id]
+	if !ok || !c.Enabled {
+		return nil, false
+	}
+	return c, true
+}
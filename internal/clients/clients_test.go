@@ -0,0 +1,125 @@
+package clients_test
+
+import (
+	"RBKproject4/internal/clients"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func registryFile(t *testing.T) string {
+	t.Helper()
+	content := `
+clients:
+  - id: mobile
+    enabled: true
+    tokens: ["` + clients.HashToken("mobile-token") + `", "` + clients.HashToken("mobile-token-2") + `"]
+    templates: ["CARD_*", "PDP"]
+    formats: ["pdf"]
+  - id: backoffice
+    enabled: true
+    admin: true
+    tokens: ["` + clients.HashToken("backoffice-token") + `"]
+    templates: ["*"]
+    formats: ["*"]
+  - id: retired
+    enabled: false
+    tokens: ["` + clients.HashToken("retired-token") + `"]
+    templates: ["*"]
+    formats: ["*"]
+`
+	path := filepath.Join(t.TempDir(), "clients.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRegistry_Authenticate(t *testing.T) {
+	registry, err := clients.Load(registryFile(t))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	tests := []struct {
+		token  string
+		wantID string
+	}{
+		{"mobile-token", "mobile"},
+		{"mobile-token-2", "mobile"},
+		{"backoffice-token", "backoffice"},
+		{"retired-token", ""},
+		{"wrong", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		client, ok := registry.Authenticate(tt.token)
+		if ok != (tt.wantID != "") || (ok && client.ID != tt.wantID) {
+			t.Errorf("Authenticate(%q) = %v, %v, want %q", tt.token, client, ok, tt.wantID)
+		}
+	}
+
+	if _, ok := registry.Client("retired"); ok {
+		t.Error("Client() returned a disabled client")
+	}
+}
+
+func TestClient_Allows(t *testing.T) {
+	registry, err := clients.Load(registryFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mobile, _ := registry.Client("mobile")
+
+	tests := []struct {
+		code, format string
+		want         bool
+	}{
+		{"CARD_STATEMENT", "pdf", true},
+		{"PDP", "PDF", true},
+		{"PDP", "docx", false},
+		{"PDP_EXTENDED", "pdf", false},
+		{"ACCOUNT_STATEMENT", "pdf", false},
+	}
+	for _, tt := range tests {
+		if got := mobile.AllowsTemplate(tt.code) && mobile.AllowsFormat(tt.format); got != tt.want {
+			t.Errorf("mobile allows %s as %s = %v, want %v", tt.code, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	hash := clients.HashToken("token")
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no id", `clients: [{enabled: true}]`, "no id"},
+		{"twice", `clients: [{id: a}, {id: a}]`, "twice"},
+		{"plain token", `clients: [{id: a, tokens: ["token"]}]`, "sha256:"},
+		{"short hash", `clients: [{id: a, tokens: ["sha256:abcd"]}]`, "hex SHA-256"},
+		{"shared token", `clients: [{id: a, tokens: ["` + hash + `"]}, {id: b, tokens: ["` + hash + `"]}]`, "shares a token"},
+		{"bad pattern", `clients: [{id: a, templates: ["[A-"]}]`, "pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := clients.Parse([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatic(t *testing.T) {
+	registry := clients.Static("secret")
+	client, ok := registry.Authenticate("secret")
+	if !ok || client.ID != clients.DefaultClientID || !client.Admin || !client.AllowsTemplate("PDP") || !client.AllowsFormat("xlsx") {
+		t.Errorf("Authenticate() = %+v, %v, want the default client allowed everything", client, ok)
+	}
+	if _, ok := registry.Authenticate("other"); ok {
+		t.Error("Authenticate() accepted a wrong token")
+	}
+}
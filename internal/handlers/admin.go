@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	templates *renderers.Registry
+	audit     *audit.Log
+}
+
+func NewAdminHandler(templates *renderers.Registry, auditLog *audit.Log) *AdminHandler {
+	return &AdminHandler{templates: templates, audit: auditLog}
+}
+
+// TemplateStatus lists the templates held in memory with their reload count
+// and the last error loading them.
+func (h *AdminHandler) TemplateStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.templates.Status())
+}
+
+// AuditRecords lists generated documents, filtered by code, clientId and a
+// from/to time range (RFC 3339), a page of limit records after the record
+// numbered after.
+func (h *AdminHandler) AuditRecords(c *gin.Context) {
+	if h.audit == nil {
+		c.JSON(http.StatusOK, models.AuditPage{Records: []models.AuditRecord{}})
+		return
+	}
+
+	q := models.AuditQuery{Code: c.Query("code"), ClientID: c.Query("clientId")}
+	var err error
+	if q.From, err = queryTime(c, "from"); err != nil {
+		badRequest(c, "from must be an RFC 3339 time")
+		return
+	}
+	if q.To, err = queryTime(c, "to"); err != nil {
+		badRequest(c, "to must be an RFC 3339 time")
+		return
+	}
+	if v := c.Query("after"); v != "" {
+		if q.After, err = strconv.ParseUint(v, 10, 64); err != nil {
+			badRequest(c, "after must be a record number")
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			badRequest(c, "limit must be a positive number")
+			return
+		}
+	}
+
+	page, err := h.audit.Query(c.Request.Context(), q)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// VerifyAudit checks the hash chain of the whole audit log.
+func (h *AdminHandler) VerifyAudit(c *gin.Context) {
+	if h.audit == nil {
+		c.JSON(http.StatusOK, models.AuditVerification{Valid: true})
+		return
+	}
+	result, err := h.audit.Verify(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func queryTime(c *gin.Context, name string) (time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/problem"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DocumentHandler) GenerateBatch(c *gin.Context) {
+	var items []models.RequestBody
+	if err := c.ShouldBindJSON(&items); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	if len(items) == 0 {
+		badRequest(c, "batch is empty")
+		return
+	}
+	if limit := h.svc.MaxBatchItems(); len(items) > limit {
+		problem.Write(c, http.StatusRequestEntityTooLarge, problem.CodeBatchTooLarge, fmt.Sprintf("batch exceeds %d items", limit), gin.H{"limit": limit})
+		return
+	}
+
+	// a large batch takes far longer than the server's WriteTimeout allows
+	// a response, so it gets a deadline of its own
+	timeout := h.svc.BatchTimeout()
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		slog.WarnContext(ctx, "cannot extend the batch write deadline", "error", err)
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename=batch.zip")
+	c.Status(http.StatusOK)
+
+	// the archive is streamed, so once it has started a failure can only be
+	// reported by cutting the response short
+	if _, err := h.svc.GenerateBatch(ctx, items, c.Writer); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
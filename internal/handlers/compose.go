@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DocumentHandler) Compose(c *gin.Context) {
+	var req models.ComposeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	if len(req.Parts) == 0 {
+		badRequest(c, "parts are required")
+		return
+	}
+
+	// rendering and merging many parts can outlast the server's WriteTimeout,
+	// so compose gets a deadline of its own, as a batch does
+	timeout := h.svc.ComposeTimeout()
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		slog.WarnContext(ctx, "cannot extend the compose write deadline", "error", err)
+	}
+
+	doc, err := h.svc.Compose(ctx, &req)
+	streamDocument(c, doc, err)
+}
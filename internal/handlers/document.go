@@ -1,13 +1,29 @@
 package handlers
 
 import (
+	"RBKproject4/internal/middleware"
 	"RBKproject4/internal/models"
+	"RBKproject4/internal/problem"
+	"RBKproject4/internal/renderers"
 	"RBKproject4/internal/services"
+	"RBKproject4/internal/upstream"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// UndefinedVariablesHeader lists the undefined variables of a document that
+// was rendered in lenient mode.
+const UndefinedVariablesHeader = "X-Undefined-Variables"
+
 type DocumentHandler struct {
 	svc *services.DocumentService
 }
@@ -16,107 +32,184 @@ func NewDocumentHandler(svc *services.DocumentService) *DocumentHandler {
 	return &DocumentHandler{svc: svc}
 }
 
+// writeError answers with the problem that matches err. Errors of no known
+// kind can carry internal details, so they are logged and not sent.
+func writeError(c *gin.Context, err error) {
+	var verr *services.ValidationError
+	var uerr *renderers.UndefinedVariablesError
+	var ferr *services.UnsupportedFormatError
+	var oerr *upstream.OpenError
+	var rerr *services.RenderError
+	switch {
+	case errors.As(err, &ferr):
+		// a format nothing generates is a bad request; one this template
+		// can't be generated in is an unsupported media type
+		status := http.StatusBadRequest
+		if ferr.Known {
+			status = http.StatusUnsupportedMediaType
+		}
+		problem.Write(c, status, problem.CodeUnsupportedFormat, err.Error(), gin.H{"supported": ferr.Supported})
+	case errors.As(err, &verr):
+		problem.Write(c, http.StatusUnprocessableEntity, problem.CodeInvalidData, err.Error(), gin.H{"violations": verr.Violations})
+	case errors.As(err, &uerr):
+		problem.Write(c, http.StatusUnprocessableEntity, problem.CodeUndefinedVariables, err.Error(), gin.H{"undefinedVariables": uerr.Paths})
+	case errors.Is(err, services.ErrForbidden):
+		problem.Write(c, http.StatusForbidden, problem.CodeForbidden, err.Error(), nil)
+	case errors.Is(err, services.ErrTemplateNotFound):
+		problem.Write(c, http.StatusNotFound, problem.CodeTemplateNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidData):
+		problem.Write(c, http.StatusUnprocessableEntity, problem.CodeInvalidData, err.Error(), nil)
+	case errors.Is(err, services.ErrUnsupportedFormat):
+		problem.Write(c, http.StatusBadRequest, problem.CodeUnsupportedFormat, err.Error(), nil)
+	case errors.Is(err, services.ErrUnknownEngine):
+		problem.Write(c, http.StatusBadRequest, problem.CodeUnknownEngine, err.Error(), nil)
+	case errors.Is(err, services.ErrQueueFull):
+		problem.Write(c, http.StatusServiceUnavailable, problem.CodeQueueFull, err.Error(), nil)
+	case errors.As(err, &oerr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(oerr.RetryAfter.Seconds()))))
+		problem.Write(c, http.StatusServiceUnavailable, problem.CodeCircuitOpen, err.Error(), nil)
+	case errors.Is(err, services.ErrUpstreamTimeout):
+		problem.Write(c, http.StatusGatewayTimeout, problem.CodeUpstreamTimeout, err.Error(), nil)
+	case errors.Is(err, services.ErrUpstreamUnavailable):
+		problem.Write(c, http.StatusBadGateway, problem.CodeUpstreamUnavailable, err.Error(), nil)
+	case errors.As(err, &rerr):
+		// our own template failed, which is no upstream's fault
+		problem.Write(c, http.StatusInternalServerError, problem.CodeRenderFailed, rerr.Error(), nil)
+	case errors.Is(err, services.ErrRenderFailed):
+		problem.Write(c, http.StatusBadGateway, problem.CodeRenderFailed, err.Error(), nil)
+	default:
+		slog.ErrorContext(c.Request.Context(), "request failed", "path", c.Request.URL.Path, "error", err)
+		problem.Write(c, http.StatusInternalServerError, problem.CodeInternal, "", nil)
+	}
+}
+
+// badRequest answers a request whose body can't be used.
+func badRequest(c *gin.Context, detail string) {
+	problem.Write(c, http.StatusBadRequest, problem.CodeBadRequest, detail, nil)
+}
+
 func streamDocument(c *gin.Context, doc *models.Document, err error) {
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	if len(doc.Warnings) > 0 {
+		c.Header(UndefinedVariablesHeader, strings.Join(doc.Warnings, ", "))
+	}
+
 	c.DataFromReader(
 		http.StatusOK,
 		int64(len(doc.Data)),
 		doc.ContentType(),
 		bytes.NewReader(doc.Data),
 		map[string]string{
-			"Content-Disposition": "attachment; filename=" + doc.Filename,
+			"Content-Disposition": contentDisposition(doc.Filename),
+			"ETag":                etag(doc.Data),
 		},
 	)
 }
 
-func (h *DocumentHandler) GenerateHTML(c *gin.Context) {
-	var req models.RequestBody
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	ctx := c.Request.Context()
-	var doc *models.Document
-	var err error
-
-	switch req.Format {
-	case "html":
-		doc, err = h.svc.GenerateHTML(ctx, &req)
-	case "pdf":
-		doc, err = h.svc.GeneratePDF(ctx, &req)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
-		return
-	}
+// etag is a strong entity tag for a generated document.
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// GenerateDocument renders the template named in the path. The format is the
+// format query parameter if there is one, otherwise the one of the code's
+// outputs that the Accept header prefers. The body carries the data and
+// options; its code and format are ignored.
+func (h *DocumentHandler) GenerateDocument(c *gin.Context) {
+	var req models.RequestBody
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(c, err.Error())
 		return
 	}
+	req.Code = c.Param("code")
+	req.Format = c.Query("format")
 
-	streamDocument(c, doc, err)
+	h.generate(c, &req)
 }
 
-func (h *DocumentHandler) GenerateDocument(c *gin.Context) {
+// Generate serves the /generate routes, kept as aliases of GenerateDocument
+// for clients that name the code and format in the body.
+func (h *DocumentHandler) Generate(c *gin.Context) {
 	var req models.RequestBody
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
+	h.generate(c, &req)
+}
+
+// generate renders req, negotiating the format from the Accept header when
+// the request doesn't name one.
+func (h *DocumentHandler) generate(c *gin.Context, req *models.RequestBody) {
 	ctx := c.Request.Context()
-	var doc *models.Document
-	var err error
-
-	switch req.Format {
-	case "docx":
-		doc, err = h.svc.GenerateDOCX(ctx, &req)
-	case "pdf":
-		doc, err = h.svc.GeneratePDF(ctx, &req)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"})
-		return
+	if req.Format == "" {
+		details, err := h.svc.GetTemplate(ctx, req.Code)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		format, ok := negotiateFormat(c.GetHeader("Accept"), details.Outputs)
+		if !ok {
+			problem.Write(c, http.StatusNotAcceptable, problem.CodeNotAcceptable,
+				"none of the accepted media types can be generated from "+req.Code,
+				gin.H{"supported": details.Outputs})
+			return
+		}
+		req.Format = format
 	}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	doc, err := h.svc.Generate(ctx, req)
+	// unknown codes and formats are left out, so that clients can't add
+	// label values; the code is checked whatever failed, as some errors come
+	// before the template is looked up
+	if !errors.Is(err, services.ErrForbidden) && h.svc.HasTemplate(req.Code) {
+		c.Set(middleware.TemplateCodeKey, req.Code)
+	}
+	if h.svc.KnowsFormat(req.Format) {
+		c.Set(middleware.FormatKey, req.Format)
 	}
-
 	streamDocument(c, doc, err)
 }
 
-func (h *DocumentHandler) GenerateXLSX(c *gin.Context) {
-	var req models.RequestBody
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *DocumentHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.svc.ListTemplates(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
 
-	if req.Format != "xlsx" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"})
+func (h *DocumentHandler) GetTemplate(c *gin.Context) {
+	template, err := h.svc.GetTemplate(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		writeError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, template)
+}
 
-	doc, err := h.svc.GenerateXLSX(c.Request.Context(), &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func (h *DocumentHandler) Validate(c *gin.Context) {
+	var req models.RequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
 		return
 	}
 
-	streamDocument(c, doc, err)
-}
+	if _, err := h.svc.GetTemplate(c.Request.Context(), req.Code); err != nil {
+		writeError(c, err)
+		return
+	}
 
-func (h *DocumentHandler) ListTemplates(c *gin.Context) {
-	templates, err := h.svc.ListTemplates(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.Validate(c.Request.Context(), &req); err != nil {
+		writeError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"templates": templates})
+	c.JSON(http.StatusOK, gin.H{"valid": true})
 }
@@ -0,0 +1,208 @@
+package handlers_test
+
+import (
+	"RBKproject4/internal/handlers"
+	"RBKproject4/internal/middleware"
+	"RBKproject4/internal/problem"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newDocumentRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"greet.html":          "Hello {{ name }}!",
+		"greet.manifest.yaml": "filename: \"привет {{ name }}\"\n",
+		"broken.html":         "{% include \"missing_part.html\" %}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/documents/:code", handlers.NewDocumentHandler(svc).GenerateDocument)
+	return router
+}
+
+func TestGenerateDocument_Negotiation(t *testing.T) {
+	router := newDocumentRouter(t)
+
+	tests := []struct {
+		name, target, accept string
+		status               int
+		contentType          string
+	}{
+		{"no accept header", "/documents/greet", "", http.StatusOK, "text/html"},
+		{"preferred type", "/documents/greet", "application/pdf;q=0.5, text/html", http.StatusOK, "text/html"},
+		{"type wildcard", "/documents/greet", "text/*", http.StatusOK, "text/html"},
+		{"excluded type", "/documents/greet", "text/html;q=0, application/json", http.StatusNotAcceptable, ""},
+		{"nothing acceptable", "/documents/greet", "image/png", http.StatusNotAcceptable, ""},
+		{"query beats accept", "/documents/greet?format=html", "image/png", http.StatusOK, "text/html"},
+		{"unknown query format", "/documents/greet?format=odt", "", http.StatusBadRequest, ""},
+		{"missing template", "/documents/missing", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(`{"data": {"name": "Anna"}}`))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.contentType)
+			}
+		})
+	}
+}
+
+func TestGenerateDocument_Headers(t *testing.T) {
+	router := newDocumentRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/documents/greet", strings.NewReader(`{"data": {"name": "Anna"}}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	sum := sha256.Sum256([]byte("Hello Anna!"))
+	want := map[string]string{
+		"Content-Length":      "11",
+		"ETag":                `"` + hex.EncodeToString(sum[:]) + `"`,
+		"Content-Disposition": `attachment; filename="______ Anna.html"; filename*=UTF-8''%D0%BF%D1%80%D0%B8%D0%B2%D0%B5%D1%82%20Anna.html`,
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
+
+func TestGenerateDocument_EmptyBody(t *testing.T) {
+	router := newDocumentRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents/greet", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "Hello !" {
+		t.Errorf("status = %d, body = %q", rec.Code, rec.Body)
+	}
+}
+
+func TestGenerateDocument_Problem(t *testing.T) {
+	router := newDocumentRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/documents/missing", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, problem.ContentType) {
+		t.Errorf("Content-Type = %q, want %s", ct, problem.ContentType)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"status":    float64(http.StatusNotFound),
+		"code":      problem.CodeTemplateNotFound,
+		"requestId": "req-42",
+		"instance":  "/documents/missing",
+	}
+	for name, value := range want {
+		if body[name] != value {
+			t.Errorf("%s = %v, want %v", name, body[name], value)
+		}
+	}
+}
+
+func TestGenerateDocument_RenderFailure(t *testing.T) {
+	router := newDocumentRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/documents/broken?format=html", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != problem.CodeRenderFailed {
+		t.Errorf("code = %v, want %s", body["code"], problem.CodeRenderFailed)
+	}
+	// the pongo2 error names the template files
+	if detail, _ := body["detail"].(string); strings.Contains(detail, "missing_part") || strings.Contains(detail, "/") {
+		t.Errorf("detail = %q, leaks the render error", detail)
+	}
+}
+
+func TestGenerateDocument_TemplateCodeLabel(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello {{ name }}!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := services.NewDocumentService(slog.New(slog.NewTextHandler(io.Discard, nil)), renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	var label string
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		label = c.GetString(middleware.TemplateCodeKey)
+	})
+	router.POST("/documents/:code", handlers.NewDocumentHandler(svc).GenerateDocument)
+
+	tests := []struct {
+		path, body string
+		wantStatus int
+		wantLabel  string
+	}{
+		{"/documents/greet?format=html", `{"data": {"name": "World"}}`, http.StatusOK, "greet"},
+		{"/documents/greet?format=html", `{"data": [1]}`, http.StatusUnprocessableEntity, "greet"},
+		// data is checked before the template is looked up
+		{"/documents/random-1234?format=html", `{"data": [1]}`, http.StatusUnprocessableEntity, ""},
+		{"/documents/random-1234?format=html", `{}`, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		label = ""
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.wantStatus || label != tt.wantLabel {
+			t.Errorf("%s %s: status %d, label %q; want %d, %q", tt.path, tt.body, rec.Code, label, tt.wantStatus, tt.wantLabel)
+		}
+	}
+}
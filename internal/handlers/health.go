@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"RBKproject4/internal/health"
+	"RBKproject4/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez answers as long as the process serves requests at all.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": models.HealthOK})
+}
+
+// Readyz reports every dependency and fails with 503 when one of them is
+// failing or the service is shutting down.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checker.Report(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != models.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
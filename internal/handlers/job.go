@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/problem"
+	"RBKproject4/internal/services"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	svc *services.JobService
+}
+
+func NewJobHandler(svc *services.JobService) *JobHandler {
+	return &JobHandler{svc: svc}
+}
+
+func (h *JobHandler) CreateJob(c *gin.Context) {
+	var req models.RequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			badRequest(c, "callbackUrl must be an absolute http(s) URL")
+			return
+		}
+	}
+
+	job, err := h.svc.Submit(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		problem.Write(c, http.StatusNotFound, problem.CodeJobNotFound, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *JobHandler) GetJobResult(c *gin.Context) {
+	doc, err := h.svc.Result(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		problem.Write(c, http.StatusNotFound, problem.CodeJobNotFound, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrJobNotReady):
+		problem.Write(c, http.StatusConflict, problem.CodeJobNotReady, err.Error(), nil)
+		return
+	}
+
+	streamDocument(c, doc, err)
+}
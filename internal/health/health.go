@@ -0,0 +1,155 @@
+// Package health checks whether the dependencies of the service are usable, so
+// that traffic is only sent to instances that can serve it.
+package health
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc reports why a dependency can't be used, or nil when it can.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	run  CheckFunc
+}
+
+// Checker runs the registered checks and keeps their report for a while, so
+// frequent probes don't load the dependencies.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	checks  []check
+
+	mu     sync.Mutex
+	report *models.ReadinessReport
+
+	draining atomic.Bool
+}
+
+// NewChecker makes a checker whose reports are reused for ttl and whose
+// checks each get timeout to finish.
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Add registers a check under name. Checks must be added before the first
+// report.
+func (c *Checker) Add(name string, run CheckFunc) {
+	c.checks = append(c.checks, check{name: name, run: run})
+}
+
+// Drain makes every later report fail, so that load balancers stop sending
+// requests while the service shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Report returns the latest report, running the checks again when it is
+// older than the checker's ttl. Checks run concurrently.
+func (c *Checker) Report(ctx context.Context) *models.ReadinessReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report == nil || time.Since(c.report.CheckedAt) >= c.ttl {
+		c.report = c.run(ctx)
+	}
+
+	report := *c.report
+	if c.draining.Load() {
+		report.Status = models.HealthFailing
+		report.Draining = true
+	}
+	return &report
+}
+
+func (c *Checker) run(ctx context.Context) *models.ReadinessReport {
+	report := &models.ReadinessReport{
+		Status:    models.HealthOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]models.CheckResult, len(c.checks)),
+	}
+
+	results := make([]models.CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.run(checkCtx)
+			results[i] = models.CheckResult{
+				Status:    models.HealthOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = models.HealthFailing
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, chk := range c.checks {
+		report.Checks[chk.name] = results[i]
+		if results[i].Status != models.HealthOK {
+			report.Status = models.HealthFailing
+		}
+	}
+	return report
+}
+
+// HTTPCheck passes when a GET of target answers with a 2xx status.
+func HTTPCheck(client *http.Client, target string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			// the report is public, so leave the URL out
+			var uerr *url.Error
+			if errors.As(err, &uerr) {
+				err = uerr.Err
+			}
+			return fmt.Errorf("unreachable: %w", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("returned status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// DirCheck passes when dir can be listed. Why it can't is logged rather than
+// reported, as the error names the directory.
+func DirCheck(logger *slog.Logger, dir string) CheckFunc {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context) error {
+		if _, err := os.ReadDir(dir); err != nil {
+			logger.WarnContext(ctx, "directory not readable", "dir", dir, "error", err)
+			return errors.New("directory not readable")
+		}
+		return nil
+	}
+}
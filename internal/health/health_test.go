@@ -0,0 +1,108 @@
+package health_test
+
+import (
+	"RBKproject4/internal/health"
+	"RBKproject4/internal/models"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecker_Report(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name   string
+		check  health.CheckFunc
+		status string
+	}{
+		{"reachable", health.HTTPCheck(up.Client(), up.URL+"/health"), models.HealthOK},
+		{"failing", health.HTTPCheck(down.Client(), down.URL+"/health"), models.HealthFailing},
+		{"readable dir", health.DirCheck(logger, t.TempDir()), models.HealthOK},
+		{"missing dir", health.DirCheck(logger, missing), models.HealthFailing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(time.Minute, time.Second)
+			checker.Add("dependency", tt.check)
+
+			report := checker.Report(context.Background())
+			if report.Status != tt.status || report.Checks["dependency"].Status != tt.status {
+				t.Errorf("report = %+v, want %s", report, tt.status)
+			}
+			if tt.status == models.HealthFailing && report.Checks["dependency"].Error == "" {
+				t.Errorf("failing check has no error")
+			}
+			// the report is public
+			if strings.Contains(report.Checks["dependency"].Error, missing) {
+				t.Errorf("error names the directory: %s", report.Checks["dependency"].Error)
+			}
+		})
+	}
+}
+
+func TestChecker_CachesReport(t *testing.T) {
+	runs := 0
+	checker := health.NewChecker(50*time.Millisecond, time.Second)
+	checker.Add("counter", func(context.Context) error {
+		runs++
+		return nil
+	})
+
+	checker.Report(context.Background())
+	checker.Report(context.Background())
+	if runs != 1 {
+		t.Errorf("checks ran %d times within the ttl, want 1", runs)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	checker.Report(context.Background())
+	if runs != 2 {
+		t.Errorf("checks ran %d times after the ttl, want 2", runs)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := health.NewChecker(time.Minute, 10*time.Millisecond)
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Report(context.Background())
+	if report.Checks["slow"].Status != models.HealthFailing {
+		t.Errorf("slow check = %+v, want failing", report.Checks["slow"])
+	}
+}
+
+func TestChecker_Drain(t *testing.T) {
+	checker := health.NewChecker(time.Minute, time.Second)
+	checker.Add("ok", func(context.Context) error { return nil })
+
+	if report := checker.Report(context.Background()); report.Status != models.HealthOK {
+		t.Fatalf("status = %s before draining, want ok", report.Status)
+	}
+
+	checker.Drain()
+	report := checker.Report(context.Background())
+	if report.Status != models.HealthFailing || !report.Draining {
+		t.Errorf("report = %+v while draining, want failing", report)
+	}
+	if report.Checks["ok"].Status != models.HealthOK {
+		t.Errorf("dependency checks should still be reported: %+v", report.Checks)
+	}
+}
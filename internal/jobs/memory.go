@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"sync"
+)
+
+type MemoryStore struct {
+	mu      sync.RWMutex
+	jobs    map[string]models.Job
+	results map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:    make(map[string]models.Job),
+		results: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Save(_ context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (s *MemoryStore) SaveResult(_ context.Context, id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	s.results[id] = data
+	return nil
+}
+
+func (s *MemoryStore) Result(_ context.Context, id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.results[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]*models.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, &job)
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	delete(s.results, id)
+	return nil
+}
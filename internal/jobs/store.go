@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"errors"
+)
+
+var ErrNotFound = errors.New("job not found")
+
+// Store keeps job state and finished documents so that they can be polled
+// after the request that submitted them has returned.
+type Store interface {
+	Save(ctx context.Context, job *models.Job) error
+	Get(ctx context.Context, id string) (*models.Job, error)
+	SaveResult(ctx context.Context, id string, data []byte) error
+	Result(ctx context.Context, id string) ([]byte, error)
+	// List returns every stored job, for cleanup and recovery.
+	List(ctx context.Context) ([]*models.Job, error)
+	// Delete removes a job and its result. Deleting a missing job is not an
+	// error.
+	Delete(ctx context.Context, id string) error
+}
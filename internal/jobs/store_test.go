@@ -0,0 +1,127 @@
+package jobs_test
+
+import (
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, store jobs.Store) {
+	ctx := context.Background()
+
+	job := &models.Job{
+		ID:        "abc123",
+		Status:    models.JobQueued,
+		Code:      "CARD_STATEMENT",
+		Format:    "pdf",
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if err := store.Save(ctx, job); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	got, err := store.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if got.Code != job.Code || got.Status != job.Status || !got.CreatedAt.Equal(job.CreatedAt) {
+		t.Errorf("Get() = %+v, want %+v", got, job)
+	}
+
+	// the stored job must not alias the caller's copy
+	job.Status = models.JobRunning
+	got, _ = store.Get(ctx, "abc123")
+	if got.Status != models.JobQueued {
+		t.Errorf("stored job changed without Save, status = %s", got.Status)
+	}
+
+	if _, err := store.Result(ctx, "abc123"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Result() before SaveResult error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.SaveResult(ctx, "abc123", []byte("%PDF")); err != nil {
+		t.Fatalf("SaveResult() error: %v", err)
+	}
+	data, err := store.Result(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Result() error: %v", err)
+	}
+	if string(data) != "%PDF" {
+		t.Errorf("Result() = %q, want %q", data, "%PDF")
+	}
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	list, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != "abc123" {
+		t.Errorf("List() = %+v, want abc123 only", list)
+	}
+
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := store.Get(ctx, "abc123"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Result(ctx, "abc123"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Result() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Errorf("Delete() of a missing job error: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, jobs.NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+}
+
+func TestFileStore_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := jobs.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, &models.Job{ID: "j1", Status: models.JobSucceeded}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := jobs.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := reopened.Get(ctx, "j1")
+	if err != nil {
+		t.Fatalf("Get() after reopen error: %v", err)
+	}
+	if got.Status != models.JobSucceeded {
+		t.Errorf("status = %s, want %s", got.Status, models.JobSucceeded)
+	}
+}
+
+func TestFileStore_RejectsPathTraversal(t *testing.T) {
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), "../../etc/passwd"); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,34 @@
+// Package logging holds the slog handlers the service logs through.
+package logging
+
+import (
+	"RBKproject4/internal/reqctx"
+	"context"
+	"log/slog"
+)
+
+// ContextHandler adds the ID of the request a record is logged for, so that
+// every line the service writes while serving a request can be found by it.
+// Records must be logged with the request's context, e.g. by InfoContext.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := reqctx.RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
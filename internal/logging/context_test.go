@@ -0,0 +1,39 @@
+package logging_test
+
+import (
+	"RBKproject4/internal/logging"
+	"RBKproject4/internal/reqctx"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestContextHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(logging.NewContextHandler(slog.NewJSONHandler(buf, nil))).With("component", "test")
+
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "with request")
+	logger.Info("without request")
+
+	dec := json.NewDecoder(buf)
+	var lines []map[string]any
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	if lines[0]["request_id"] != "req-1" || lines[0]["component"] != "test" {
+		t.Errorf("first line = %v, want request_id and component", lines[0])
+	}
+	if _, ok := lines[1]["request_id"]; ok {
+		t.Errorf("second line = %v, want no request_id", lines[1])
+	}
+}
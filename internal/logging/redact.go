@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces every masked value.
+const Redacted = "[REDACTED]"
+
+type pattern struct {
+	re *regexp.Regexp
+	// valid, when set, filters the matches of re, e.g. by a checksum
+	valid func(match string) bool
+}
+
+// builtinPatterns are the identifiers that turn up in our payloads. Card
+// numbers come before IINs and IBANs, so that their digits are masked as one.
+var builtinPatterns = map[string]pattern{
+	"pan":   {regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}\b`), luhn},
+	"iban":  {regexp.MustCompile(`\bKZ\d{2}[0-9A-Z]{16}\b`), nil},
+	"iin":   {regexp.MustCompile(`\b\d{12}\b`), nil},
+	"email": {regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), nil},
+}
+
+var builtinOrder = []string{"pan", "iban", "iin", "email"}
+
+// RedactRules say what is masked in logs.
+type RedactRules struct {
+	// Fields are names of attributes and JSON fields whose values are
+	// masked whole. Case, "_" and "-" are ignored and "*" matches any run of
+	// characters, so "*iin" covers iin, clientIin and client_iin.
+	Fields []string
+	// Patterns are masked wherever they occur in a string. Each is the name
+	// of a built-in pattern (iin, iban, pan, email) or a regular expression.
+	Patterns []string
+}
+
+// Redactor masks personal data in log records.
+type Redactor struct {
+	fields   []string
+	patterns []pattern
+}
+
+func NewRedactor(rules RedactRules) (*Redactor, error) {
+	r := &Redactor{}
+	for _, f := range rules.Fields {
+		if f = normalizeField(f); f != "" {
+			r.fields = append(r.fields, f)
+		}
+	}
+
+	builtins := map[string]bool{}
+	var custom []pattern
+	for _, p := range rules.Patterns {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "":
+		case builtinPatterns[strings.ToLower(p)].re != nil:
+			builtins[strings.ToLower(p)] = true
+		default:
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+			}
+			custom = append(custom, pattern{re: re})
+		}
+	}
+	for _, name := range builtinOrder {
+		if builtins[name] {
+			r.patterns = append(r.patterns, builtinPatterns[name])
+		}
+	}
+	r.patterns = append(r.patterns, custom...)
+	return r, nil
+}
+
+func normalizeField(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// masksField reports whether the value of the field called name is masked.
+func (r *Redactor) masksField(name string) bool {
+	name = normalizeField(name)
+	for _, f := range r.fields {
+		if ok, _ := path.Match(f, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the patterns in s.
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			return Redacted
+		})
+	}
+	return s
+}
+
+// Attr masks a, whole when its key is a masked field.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if r.masksField(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		// an IIN or card number may well be sent as a number
+		s := v.String()
+		if v.Kind() == slog.KindFloat64 {
+			s = strconv.FormatFloat(v.Float64(), 'f', -1, 64)
+		}
+		if r.String(s) != s {
+			return slog.String(a.Key, Redacted)
+		}
+		return slog.Attr{Key: a.Key, Value: v}
+	case slog.KindGroup:
+		attrs := v.Group()
+		masked := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			masked[i] = r.Attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(masked...)}
+	case slog.KindAny:
+		return slog.Any(a.Key, r.any(v.Any()))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
+
+// any masks an arbitrary value. Structs and maps, e.g. a request body, are
+// masked field by field through their JSON form.
+func (r *Redactor) any(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return r.String(v.Error())
+	case []byte:
+		return r.String(string(v))
+	case fmt.Stringer:
+		return r.String(v.String())
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return r.String(fmt.Sprint(v))
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return r.String(string(raw))
+	}
+	return r.json(doc)
+}
+
+func (r *Redactor) json(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, fv := range v {
+			if r.masksField(k) {
+				v[k] = Redacted
+			} else {
+				v[k] = r.json(fv)
+			}
+		}
+		return v
+	case []any:
+		for i, ev := range v {
+			v[i] = r.json(ev)
+		}
+		return v
+	case string:
+		return r.String(v)
+	case json.Number:
+		if s := r.String(string(v)); s != string(v) {
+			return s
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// luhn reports whether the digits of s pass the Luhn check, as card numbers
+// do, which tells them apart from other 16 digit numbers.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// RedactHandler masks personal data in the message and attributes of every
+// record before passing it on.
+type RedactHandler struct {
+	slog.Handler
+	r *Redactor
+}
+
+func NewRedactHandler(h slog.Handler, r *Redactor) *RedactHandler {
+	return &RedactHandler{Handler: h, r: r}
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	masked := slog.NewRecord(rec.Time, rec.Level, h.r.String(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.r.Attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.r.Attr(a)
+	}
+	return &RedactHandler{Handler: h.Handler.WithAttrs(masked), r: h.r}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{Handler: h.Handler.WithGroup(name), r: h.r}
+}
@@ -0,0 +1,153 @@
+package logging_test
+
+import (
+	"RBKproject4/internal/logging"
+	"RBKproject4/internal/models"
+	"RBKproject4/pkg/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// identifiers that must never reach a log
+var identifiers = []string{
+	"900101300123",         // IIN
+	"KZ86125KZT5004100100", // IBAN
+	"4400430123456789",     // card number
+	"4400 4301 2345 6789",
+	"aigerim@example.kz",
+	"Aigerim Nurlanovna",
+}
+
+// defaultRedactor uses the rules a deployment gets by default.
+func defaultRedactor(t *testing.T) *logging.Redactor {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := logging.NewRedactor(logging.RedactRules{Fields: cfg.LogRedactFields, Patterns: cfg.LogRedactPatterns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func statementRequest() *models.RequestBody {
+	return &models.RequestBody{
+		Code:   "ACCOUNT_STATEMENT",
+		Format: "pdf",
+		Data: map[string]any{
+			"clientFullName": "Aigerim Nurlanovna",
+			"clientIin":      "900101300123",
+			"accountNumber":  "KZ86125KZT5004100100",
+			"contacts":       map[string]any{"mail": "aigerim@example.kz"},
+			"transactions": []any{
+				map[string]any{"details": "card 4400 4301 2345 6789, payer IIN 900101300123", "amount": 1500},
+				map[string]any{"details": "transfer to KZ86125KZT5004100100", "ref": 4400430123456789},
+			},
+			"period": "01.01.2024 - 31.01.2024",
+		},
+	}
+}
+
+func TestRedactHandler_RequestBody(t *testing.T) {
+	handlers := map[string]func(*bytes.Buffer) slog.Handler{
+		"json": func(buf *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(buf, nil) },
+		"text": func(buf *bytes.Buffer) slog.Handler { return slog.NewTextHandler(buf, nil) },
+	}
+
+	for name, newHandler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(logging.NewRedactHandler(newHandler(buf), defaultRedactor(t)))
+
+			req := statementRequest()
+			logger.Info("generating document", "request", req)
+			logger.With("request", req).Warn("with attrs")
+			logger.WithGroup("req").Info("grouped", "data", req.Data)
+			// errors can echo data, but only the patterns can be found in them
+			logger.Error("render failed", "error", fmt.Errorf("invalid data: %v", []any{"900101300123", "aigerim@example.kz", 4400430123456789}))
+			logger.Info("number", "ref", int64(900101300123), "amount", 900101300123.0)
+			logger.Info("message with iin 900101300123 and aigerim@example.kz")
+
+			out := buf.String()
+			for _, id := range identifiers {
+				if strings.Contains(out, id) {
+					t.Errorf("log contains %q:\n%s", id, out)
+				}
+			}
+			for _, kept := range []string{"ACCOUNT_STATEMENT", "01.01.2024 - 31.01.2024", "1500", logging.Redacted} {
+				if !strings.Contains(out, kept) {
+					t.Errorf("log lost %q:\n%s", kept, out)
+				}
+			}
+			// the original request is left alone
+			if req.Data.(map[string]any)["clientIin"] != "900101300123" {
+				t.Error("redaction changed the logged request")
+			}
+		})
+	}
+}
+
+func TestRedactor_String(t *testing.T) {
+	r, err := logging.NewRedactor(logging.RedactRules{Patterns: []string{"iin", "iban", "pan", "email", `\+7\d{10}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"IIN 900101300123", "IIN [REDACTED]"},
+		{"iin=900101300123;", "iin=[REDACTED];"},
+		{"account KZ86125KZT5004100100", "account [REDACTED]"},
+		{"card 4400430123456789", "card [REDACTED]"},
+		{"card 4400-4301-2345-6789", "card [REDACTED]"},
+		{"mail a.b+c@bank.kz now", "mail [REDACTED] now"},
+		{"call +77011234567", "call [REDACTED]"},
+		// not Luhn valid, so not a card number
+		{"ref 4400430123456788", "ref 4400430123456788"},
+		// part of a longer number or ID
+		{"id 1234567890123", "id 1234567890123"},
+		{"job 5f0c2d9e123456789012ab", "job 5f0c2d9e123456789012ab"},
+	}
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactor_Fields(t *testing.T) {
+	r, err := logging.NewRedactor(logging.RedactRules{Fields: []string{"*iin", "full_name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(logging.NewRedactHandler(slog.NewJSONHandler(buf, nil), r))
+	logger.Info("fields", "client_iin", "x1", "FullName", "x2", "data", map[string]any{"clientIIN": "x3", "code": "PDP"})
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	data := line["data"].(map[string]any)
+	if line["client_iin"] != logging.Redacted || line["FullName"] != logging.Redacted || data["clientIIN"] != logging.Redacted {
+		t.Errorf("fields not masked: %v", line)
+	}
+	if data["code"] != "PDP" {
+		t.Errorf("code = %v, want PDP", data["code"])
+	}
+}
+
+func TestNewRedactor_InvalidPattern(t *testing.T) {
+	if _, err := logging.NewRedactor(logging.RedactRules{Patterns: []string{"("}}); err == nil {
+		t.Error("want an error for an invalid pattern")
+	}
+}
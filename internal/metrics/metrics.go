@@ -0,0 +1,192 @@
+// Package metrics exposes Prometheus metrics of the service: the HTTP traffic,
+// every document generated, template rendering, the calls to the Python
+// service and Gotenberg, and the template caches.
+package metrics
+
+import (
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/upstream"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "docgen"
+
+// documentSizeBuckets go from 1 KiB to 64 MiB.
+var documentSizeBuckets = prometheus.ExponentialBuckets(1024, 4, 9)
+
+// durationBuckets cover quick HTML renders up to PDFs of large statements.
+var durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30}
+
+// Metrics holds the collectors of the service in a registry of its own. A nil
+// *Metrics records nothing, so instrumented code doesn't need to check.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	generations      *prometheus.HistogramVec
+	inFlight         prometheus.Gauge
+	documentSize     *prometheus.HistogramVec
+	renderDuration   *prometheus.HistogramVec
+	upstreamDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, client, template code, output format and status.",
+		}, []string{"route", "client", "code", "format", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to answer HTTP requests by route, client, template code, output format and status.",
+			Buckets:   durationBuckets,
+		}, []string{"route", "client", "code", "format", "status"}),
+		generations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "generation_duration_seconds",
+			Help:      "Time to generate a document by template code, output format and result.",
+			Buckets:   durationBuckets,
+		}, []string{"code", "format", "result"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "generations_in_flight",
+			Help:      "Documents being generated.",
+		}),
+		documentSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "document_size_bytes",
+			Help:      "Size of generated documents by output format.",
+			Buckets:   documentSizeBuckets,
+		}, []string{"format"}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "template_render_duration_seconds",
+			Help:      "Time to render a template in-process by engine.",
+			Buckets:   durationBuckets,
+		}, []string{"engine"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Time of requests to the Python service and Gotenberg by upstream and status.",
+			Buckets:   durationBuckets,
+		}, []string{"upstream", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.generations, m.inFlight,
+		m.documentSize, m.renderDuration, m.upstreamDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an answered HTTP request. client is empty for
+// requests that aren't authenticated, code and format for requests that
+// don't generate a document.
+func (m *Metrics) ObserveRequest(route, client, code, format string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	labels := prometheus.Labels{"route": route, "client": client, "code": code, "format": format, "status": strconv.Itoa(status)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(d.Seconds())
+}
+
+// StartGeneration records a document generation starting. The returned
+// function records its end, with the size of the document on success.
+func (m *Metrics) StartGeneration(code, format string) func(size int, err error) {
+	if m == nil {
+		return func(int, error) {}
+	}
+	start := time.Now()
+	m.inFlight.Inc()
+	return func(size int, err error) {
+		m.inFlight.Dec()
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		m.generations.WithLabelValues(code, format, result).Observe(time.Since(start).Seconds())
+		if err == nil {
+			m.documentSize.WithLabelValues(format).Observe(float64(size))
+		}
+	}
+}
+
+// ObserveRender records a template rendered in-process by engine, e.g.
+// "pongo2".
+func (m *Metrics) ObserveRender(engine string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.renderDuration.WithLabelValues(engine).Observe(d.Seconds())
+}
+
+// Transport measures the requests base sends to the upstream called name.
+func (m *Metrics) Transport(name string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := base.RoundTrip(req)
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		m.upstreamDuration.WithLabelValues(name, status).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WatchBreaker exports the state of an upstream's breaker: 0 closed, 1 open,
+// 2 half-open.
+func (m *Metrics) WatchBreaker(c *upstream.Client) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "upstream_circuit_state",
+		Help:        "State of the upstream's circuit breaker: 0 closed, 1 open, 2 half-open.",
+		ConstLabels: prometheus.Labels{"upstream": c.Name()},
+	}, func() float64 {
+		return float64(c.State())
+	}))
+}
+
+// WatchCache exports the hits and misses of a template cache.
+func (m *Metrics) WatchCache(cache string, stats *renderers.CacheStats) {
+	for result, count := range map[string]func() uint64{"hit": stats.Hits, "miss": stats.Misses} {
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "template_cache_lookups_total",
+			Help:        "Lookups of the template caches by cache and result.",
+			ConstLabels: prometheus.Labels{"cache": cache, "result": result},
+		}, func() float64 {
+			return float64(count())
+		}))
+	}
+}
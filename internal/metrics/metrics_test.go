@@ -0,0 +1,92 @@
+package metrics_test
+
+import (
+	"RBKproject4/internal/metrics"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/upstream"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want the text format", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+
+	m.ObserveRequest("/documents/:code", "mobile", "PDP", "pdf", http.StatusOK, 120*time.Millisecond)
+	done := m.StartGeneration("PDP", "pdf")
+	if out := scrape(t, m); !strings.Contains(out, "docgen_generations_in_flight 1") {
+		t.Errorf("generation not in flight:\n%s", out)
+	}
+	done(2048, nil)
+	m.StartGeneration("PDP", "docx")(0, errors.New("boom"))
+	m.ObserveRender("pongo2", 3*time.Millisecond)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: m.Transport("gotenberg", nil)}
+	gotenberg := upstream.New("gotenberg", client, upstream.Options{FailureThreshold: 1, OpenDuration: time.Minute})
+	m.WatchBreaker(gotenberg)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if resp, err := gotenberg.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "PDP.html"), []byte("<p></p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := renderers.NewRegistry(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.WatchCache("registry", registry.CacheStats())
+	registry.Template("PDP.html")
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`docgen_http_requests_total{client="mobile",code="PDP",format="pdf",route="/documents/:code",status="200"} 1`,
+		`docgen_http_request_duration_seconds_count{client="mobile",code="PDP",format="pdf",route="/documents/:code",status="200"} 1`,
+		`docgen_generation_duration_seconds_count{code="PDP",format="pdf",result="success"} 1`,
+		`docgen_generation_duration_seconds_count{code="PDP",format="docx",result="error"} 1`,
+		`docgen_generations_in_flight 0`,
+		`docgen_document_size_bytes_sum{format="pdf"} 2048`,
+		`docgen_template_render_duration_seconds_count{engine="pongo2"} 1`,
+		`docgen_upstream_request_duration_seconds_count{status="503",upstream="gotenberg"} 1`,
+		`docgen_upstream_circuit_state{upstream="gotenberg"} 1`,
+		`docgen_template_cache_lookups_total{cache="registry",result="hit"} 1`,
+		`docgen_template_cache_lookups_total{cache="registry",result="miss"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *metrics.Metrics
+	m.ObserveRequest("/generate", "", "", "", http.StatusOK, time.Second)
+	m.StartGeneration("PDP", "pdf")(10, nil)
+	m.ObserveRender("pongo2", time.Second)
+	if m.Transport("python", http.DefaultTransport) != http.DefaultTransport {
+		t.Error("nil metrics should not wrap the transport")
+	}
+}
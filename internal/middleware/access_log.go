@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs one line per request with its route, template code, output
+// format, client, status, response size and duration. Server errors are
+// logged as errors and client errors as warnings.
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		// the request now carries everything set on its context on the way,
+		// the request ID included
+		logger.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route(c)),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("code", c.GetString(TemplateCodeKey)),
+			slog.String("format", c.GetString(FormatKey)),
+			slog.String("client", c.GetString(ClientIDKey)),
+			slog.Int("status", status),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}
+}
+
+// route is the pattern of the route that matched, for logs and metrics that
+// mustn't have a series per path.
+func route(c *gin.Context) string {
+	if r := c.FullPath(); r != "" {
+		return r
+	}
+	return "unmatched"
+}
@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"RBKproject4/internal/logging"
+	"RBKproject4/internal/middleware"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := &bytes.Buffer{}
+	logger := slog.New(logging.NewContextHandler(slog.NewJSONHandler(buf, nil)))
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.AccessLog(logger))
+	router.POST("/documents/:code", func(c *gin.Context) {
+		c.Set(middleware.ClientIDKey, "mobile")
+		c.Set(middleware.TemplateCodeKey, c.Param("code"))
+		c.Set(middleware.FormatKey, "pdf")
+		c.String(http.StatusOK, "%PDF")
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/documents/PDP", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-7")
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "req-7" {
+		t.Errorf("X-Request-ID = %q, want req-7", got)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line %q: %v", buf, err)
+	}
+	want := map[string]any{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "POST",
+		"route":      "/documents/:code",
+		"path":       "/documents/PDP",
+		"code":       "PDP",
+		"format":     "pdf",
+		"client":     "mobile",
+		"status":     float64(200),
+		"bytes":      float64(4),
+		"request_id": "req-7",
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("no duration logged")
+	}
+}
+
+func TestAccessLog_Levels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, "INFO"},
+		{http.StatusNotFound, "WARN"},
+		{http.StatusBadGateway, "ERROR"},
+	}
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		router := gin.New()
+		router.Use(middleware.AccessLog(slog.New(slog.NewJSONHandler(buf, nil))))
+		router.GET("/", func(c *gin.Context) { c.Status(tt.status) })
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q: %v", buf, err)
+		}
+		if line["level"] != tt.want {
+			t.Errorf("status %d logged at %v, want %s", tt.status, line["level"], tt.want)
+		}
+	}
+}
@@ -1,35 +1,62 @@
 package middleware
 
 import (
+	"RBKproject4/internal/clients"
+	"RBKproject4/internal/problem"
+	"RBKproject4/internal/reqctx"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(staticToken string) gin.HandlerFunc {
+const (
+	ClientIDKey = "clientID"
+	// ClientKey holds the *clients.Client of the request.
+	ClientKey = "client"
+)
+
+// AuthMiddleware authenticates the bearer token against registry and records
+// the client in the gin and request contexts. What the client may generate
+// is checked by the document service.
+func AuthMiddleware(registry *clients.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Bearer token required")
 			return
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		if token != staticToken {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		client, ok := registry.Authenticate(token)
+		if !ok {
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
 			return
 		}
 
+		c.Set(ClientIDKey, client.ID)
+		c.Set(ClientKey, client)
+		c.Request = c.Request.WithContext(reqctx.WithClientID(c.Request.Context(), client.ID))
+
+		c.Next()
+	}
+}
+
+// RequireAdmin lets only admin clients through. It must run after
+// AuthMiddleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, _ := c.Value(ClientKey).(*clients.Client)
+		if client == nil || !client.Admin {
+			problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, "admin access required")
+			return
+		}
 		c.Next()
 	}
 }
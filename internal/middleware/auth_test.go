@@ -0,0 +1,69 @@
+package middleware_test
+
+import (
+	"RBKproject4/internal/clients"
+	"RBKproject4/internal/middleware"
+	"RBKproject4/internal/reqctx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry, err := clients.Parse([]byte(`
+clients:
+  - id: mobile
+    enabled: true
+    tokens: ["` + clients.HashToken("mobile-token") + `"]
+  - id: backoffice
+    enabled: true
+    admin: true
+    tokens: ["` + clients.HashToken("backoffice-token") + `"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	api := router.Group("/", middleware.AuthMiddleware(registry))
+	api.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ClientIDKey)+" "+reqctx.ClientID(c.Request.Context()))
+	})
+	api.GET("/admin", middleware.RequireAdmin(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		path       string
+		header     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/whoami", "", http.StatusUnauthorized, ""},
+		{"/whoami", "Basic bW9iaWxl", http.StatusUnauthorized, ""},
+		{"/whoami", "Bearer wrong", http.StatusUnauthorized, ""},
+		{"/whoami", "Bearer mobile-token", http.StatusOK, "mobile mobile"},
+		{"/admin", "Bearer mobile-token", http.StatusForbidden, ""},
+		{"/admin", "Bearer backoffice-token", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"RBKproject4/internal/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers that generate a document set these keys, so that the request is
+// measured and logged with its template code and output format.
+const (
+	TemplateCodeKey = "templateCode"
+	FormatKey       = "format"
+)
+
+// Metrics records every request in m by route, client, template code, output
+// format and status.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		m.ObserveRequest(route(c), c.GetString(ClientIDKey), c.GetString(TemplateCodeKey), c.GetString(FormatKey), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// AuditRecord is one generated document in the audit log. Hash is the
+// SHA-256 of the record with Hash left empty, PrevHash included, so that a
+// record changed, removed or inserted later breaks the chain.
+type AuditRecord struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	ClientID  string    `json:"clientId"`
+	RequestID string    `json:"requestId,omitempty"`
+	Code      string    `json:"code"`
+	// TemplateVersion is the SHA-256 of the template file the document was
+	// rendered from or, with a header, footer or assets, of the sha256sum
+	// listing of all of them; empty for composed documents.
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	Format          string `json:"format"`
+	DataSHA256      string `json:"dataSha256"`
+	OutputSHA256    string `json:"outputSha256"`
+	Size            int    `json:"size"`
+	PrevHash        string `json:"prevHash"`
+	Hash            string `json:"hash"`
+}
+
+// AuditQuery selects audit records. Zero fields match everything.
+type AuditQuery struct {
+	Code     string
+	ClientID string
+	From     time.Time // inclusive
+	To       time.Time // exclusive
+	After    uint64    // only records with a greater Seq, for paging
+	Limit    int
+}
+
+type AuditPage struct {
+	Records []AuditRecord `json:"records"`
+	// Next is the After of the following page, zero on the last one.
+	Next uint64 `json:"next,omitempty"`
+}
+
+// AuditVerification is the result of checking the whole hash chain.
+type AuditVerification struct {
+	Valid   bool   `json:"valid"`
+	Records uint64 `json:"records"`
+	// BrokenAt is the Seq of the first record that doesn't fit the chain.
+	BrokenAt uint64 `json:"brokenAt,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+type BatchItemResult struct {
+	Index   int    `json:"index"`
+	Code    string `json:"code"`
+	Format  string `json:"format"`
+	File    string `json:"file,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+	Size    int    `json:"size,omitempty"`
+}
+
+type BatchManifest struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+}
@@ -0,0 +1,17 @@
+package models
+
+type ComposePart struct {
+	Code      string `json:"code"`
+	Data      any    `json:"data"`
+	Title     string `json:"title,omitempty"`
+	Landscape bool   `json:"landscape,omitempty"`
+
+	PDF *PDFOptions `json:"pdfOptions,omitempty"`
+}
+
+type ComposeRequest struct {
+	Filename string        `json:"filename,omitempty"`
+	Parts    []ComposePart `json:"parts"`
+
+	StrictVariables *bool `json:"strictVariables,omitempty"`
+}
@@ -1,5 +1,6 @@
 package models
 
+// DocumentFormat is the content type of a generated document.
 type DocumentFormat string
 
 const (
@@ -13,19 +14,14 @@ type Document struct {
 	Data     []byte
 	Format   DocumentFormat
 	Filename string
+
+	// Warnings lists the undefined variables of a leniently rendered template.
+	Warnings []string
 }
 
 func (d *Document) ContentType() string {
-	switch d.Format {
-	case FormatPDF:
-		return "application/pdf"
-	case FormatDOCX:
-		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-	case FormatXLSX:
-		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
-	case FormatHTML:
-		return "text/html"
-	default:
+	if d.Format == "" {
 		return "application/octet-stream"
 	}
+	return string(d.Format)
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Health states of the service and of each of its dependencies.
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// ReadinessReport is the result of checking every dependency the service
+// needs to serve requests.
+type ReadinessReport struct {
+	Status    string                 `json:"status"`
+	Draining  bool                   `json:"draining,omitempty"`
+	CheckedAt time.Time              `json:"checkedAt"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of checking one dependency.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// NotificationStatus is the outcome of a job's completion callback.
+type NotificationStatus string
+
+const (
+	NotificationDelivered NotificationStatus = "delivered"
+	NotificationFailed    NotificationStatus = "failed"
+)
+
+type JobResult struct {
+	Filename string         `json:"filename"`
+	Format   DocumentFormat `json:"format"`
+	Size     int            `json:"size"`
+	Checksum string         `json:"checksum"`
+}
+
+type Job struct {
+	ID         string     `json:"id"`
+	Status     JobStatus  `json:"status"`
+	Code       string     `json:"code"`
+	Format     string     `json:"format"`
+	ClientID   string     `json:"clientId,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Result     *JobResult `json:"result,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	DurationMs int64      `json:"durationMs,omitempty"`
+
+	CallbackURL  string             `json:"callbackUrl,omitempty"`
+	Deliveries   []WebhookDelivery  `json:"deliveries,omitempty"`
+	Notification NotificationStatus `json:"notification,omitempty"`
+}
+
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
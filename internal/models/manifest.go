@@ -0,0 +1,37 @@
+package models
+
+// TemplateManifest is the optional <CODE>.manifest.yaml kept next to a
+// template.
+type TemplateManifest struct {
+	Title    map[string]string `json:"title,omitempty" yaml:"title,omitempty"`
+	Owner    string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Formats  []string          `json:"formats,omitempty" yaml:"formats,omitempty"`
+	Filename string            `json:"filename,omitempty" yaml:"filename,omitempty"`
+	PDF      *PDFOptions       `json:"pdf,omitempty" yaml:"pdf,omitempty"`
+	Schema   map[string]any    `json:"schema,omitempty" yaml:"schema,omitempty"`
+
+	// Engine picks what renders DOCX and XLSX sources: "python" (the default)
+	// or "native".
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+}
+
+// SupportsFormat reports whether format may be requested. A manifest that
+// lists no formats does not restrict them.
+func (m *TemplateManifest) SupportsFormat(format string) bool {
+	if len(m.Formats) == 0 {
+		return true
+	}
+	for _, f := range m.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+type TemplateDetails struct {
+	Code    string           `json:"code"`
+	Sources []string         `json:"sources"`
+	Outputs []TemplateOutput `json:"outputs"`
+	TemplateManifest
+}
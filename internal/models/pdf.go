@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PDFOptions are the Chromium page settings of a PDF. Templates declare
+// defaults in their manifest and requests may override any of them.
+type PDFOptions struct {
+	PaperSize       string   `json:"paperSize,omitempty" yaml:"paperSize,omitempty"`
+	PaperWidth      string   `json:"paperWidth,omitempty" yaml:"paperWidth,omitempty"`
+	PaperHeight     string   `json:"paperHeight,omitempty" yaml:"paperHeight,omitempty"`
+	Landscape       *bool    `json:"landscape,omitempty" yaml:"landscape,omitempty"`
+	MarginTop       string   `json:"marginTop,omitempty" yaml:"marginTop,omitempty"`
+	MarginBottom    string   `json:"marginBottom,omitempty" yaml:"marginBottom,omitempty"`
+	MarginLeft      string   `json:"marginLeft,omitempty" yaml:"marginLeft,omitempty"`
+	MarginRight     string   `json:"marginRight,omitempty" yaml:"marginRight,omitempty"`
+	Scale           *float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	PageRanges      string   `json:"pageRanges,omitempty" yaml:"pageRanges,omitempty"`
+	PrintBackground *bool    `json:"printBackground,omitempty" yaml:"printBackground,omitempty"`
+	Header          string   `json:"header,omitempty" yaml:"header,omitempty"`
+	Footer          string   `json:"footer,omitempty" yaml:"footer,omitempty"`
+}
+
+// paperSizes maps named sizes to their portrait width and height.
+var paperSizes = map[string][2]string{
+	"A3":     {"297mm", "420mm"},
+	"A4":     {"210mm", "297mm"},
+	"A5":     {"148mm", "210mm"},
+	"LETTER": {"8.5in", "11in"},
+	"LEGAL":  {"8.5in", "14in"},
+}
+
+func (o *PDFOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.PaperSize != "" {
+		if _, ok := paperSizes[strings.ToUpper(o.PaperSize)]; !ok {
+			return fmt.Errorf("unknown paper size %q", o.PaperSize)
+		}
+	}
+	if o.Scale != nil && (*o.Scale < 0.1 || *o.Scale > 2) {
+		return fmt.Errorf("scale must be between 0.1 and 2, got %v", *o.Scale)
+	}
+	return nil
+}
+
+// Merge returns a copy of o with every field set in override replacing its
+// counterpart. Either side may be nil.
+func (o *PDFOptions) Merge(override *PDFOptions) *PDFOptions {
+	merged := PDFOptions{}
+	if o != nil {
+		merged = *o
+	}
+	if override == nil {
+		return &merged
+	}
+
+	if override.PaperSize != "" {
+		// a named size replaces explicit dimensions inherited from defaults
+		merged.PaperSize = override.PaperSize
+		merged.PaperWidth, merged.PaperHeight = "", ""
+	}
+	setString(&merged.PaperWidth, override.PaperWidth)
+	setString(&merged.PaperHeight, override.PaperHeight)
+	setString(&merged.MarginTop, override.MarginTop)
+	setString(&merged.MarginBottom, override.MarginBottom)
+	setString(&merged.MarginLeft, override.MarginLeft)
+	setString(&merged.MarginRight, override.MarginRight)
+	setString(&merged.PageRanges, override.PageRanges)
+	setString(&merged.Header, override.Header)
+	setString(&merged.Footer, override.Footer)
+	if override.Landscape != nil {
+		merged.Landscape = override.Landscape
+	}
+	if override.Scale != nil {
+		merged.Scale = override.Scale
+	}
+	if override.PrintBackground != nil {
+		merged.PrintBackground = override.PrintBackground
+	}
+	return &merged
+}
+
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// ChromiumFields maps the options onto Gotenberg's Chromium form fields.
+func (o *PDFOptions) ChromiumFields() map[string]string {
+	fields := map[string]string{}
+	if o == nil {
+		return fields
+	}
+
+	if size, ok := paperSizes[strings.ToUpper(o.PaperSize)]; ok {
+		fields["paperWidth"], fields["paperHeight"] = size[0], size[1]
+	}
+	putString(fields, "paperWidth", o.PaperWidth)
+	putString(fields, "paperHeight", o.PaperHeight)
+	putString(fields, "marginTop", o.MarginTop)
+	putString(fields, "marginBottom", o.MarginBottom)
+	putString(fields, "marginLeft", o.MarginLeft)
+	putString(fields, "marginRight", o.MarginRight)
+	putString(fields, "nativePageRanges", o.PageRanges)
+	if o.Landscape != nil {
+		fields["landscape"] = strconv.FormatBool(*o.Landscape)
+	}
+	if o.Scale != nil {
+		fields["scale"] = strconv.FormatFloat(*o.Scale, 'f', -1, 64)
+	}
+	if o.PrintBackground != nil {
+		fields["printBackground"] = strconv.FormatBool(*o.PrintBackground)
+	}
+	return fields
+}
+
+// LibreOfficeFields maps the subset of options that LibreOffice understands;
+// paper size and margins come from the document itself.
+func (o *PDFOptions) LibreOfficeFields() map[string]string {
+	fields := map[string]string{}
+	if o == nil {
+		return fields
+	}
+
+	putString(fields, "nativePageRanges", o.PageRanges)
+	if o.Landscape != nil {
+		fields["landscape"] = strconv.FormatBool(*o.Landscape)
+	}
+	return fields
+}
+
+func putString(fields map[string]string, name, value string) {
+	if value != "" {
+		fields[name] = value
+	}
+}
@@ -0,0 +1,113 @@
+package models_test
+
+import (
+	"RBKproject4/internal/models"
+	"reflect"
+	"testing"
+)
+
+func boolPtr(b bool) *bool        { return &b }
+func floatPtr(f float64) *float64 { return &f }
+
+func TestPDFOptions_Merge(t *testing.T) {
+	defaults := &models.PDFOptions{
+		PaperWidth:  "100mm",
+		PaperHeight: "200mm",
+		Landscape:   boolPtr(true),
+		MarginTop:   "10mm",
+		Header:      "STATEMENT.header",
+	}
+
+	tests := []struct {
+		name     string
+		base     *models.PDFOptions
+		override *models.PDFOptions
+		want     map[string]string
+	}{
+		{
+			name: "nil on both sides",
+			want: map[string]string{},
+		},
+		{
+			name: "defaults only",
+			base: defaults,
+			want: map[string]string{
+				"paperWidth":  "100mm",
+				"paperHeight": "200mm",
+				"landscape":   "true",
+				"marginTop":   "10mm",
+			},
+		},
+		{
+			name:     "override replaces set fields only",
+			base:     defaults,
+			override: &models.PDFOptions{Landscape: boolPtr(false), MarginTop: "5mm", Scale: floatPtr(0.8)},
+			want: map[string]string{
+				"paperWidth":  "100mm",
+				"paperHeight": "200mm",
+				"landscape":   "false",
+				"marginTop":   "5mm",
+				"scale":       "0.8",
+			},
+		},
+		{
+			name:     "named paper size replaces inherited dimensions",
+			base:     defaults,
+			override: &models.PDFOptions{PaperSize: "a4", PageRanges: "1-2"},
+			want: map[string]string{
+				"paperWidth":       "210mm",
+				"paperHeight":      "297mm",
+				"landscape":        "true",
+				"marginTop":        "10mm",
+				"nativePageRanges": "1-2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base.Merge(tt.override).ChromiumFields()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChromiumFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFOptions_MergeKeepsDefaultsUntouched(t *testing.T) {
+	base := &models.PDFOptions{MarginTop: "10mm"}
+	base.Merge(&models.PDFOptions{MarginTop: "1mm"})
+	if base.MarginTop != "10mm" {
+		t.Errorf("Merge() modified its receiver: %+v", base)
+	}
+}
+
+func TestPDFOptions_LibreOfficeFields(t *testing.T) {
+	opts := &models.PDFOptions{PaperSize: "A4", MarginTop: "1cm", Landscape: boolPtr(true), PageRanges: "1"}
+	want := map[string]string{"landscape": "true", "nativePageRanges": "1"}
+	if got := opts.LibreOfficeFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LibreOfficeFields() = %v, want %v", got, want)
+	}
+}
+
+func TestPDFOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *models.PDFOptions
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"known size", &models.PDFOptions{PaperSize: "Letter"}, false},
+		{"unknown size", &models.PDFOptions{PaperSize: "B7"}, true},
+		{"scale in range", &models.PDFOptions{Scale: floatPtr(1.5)}, false},
+		{"scale too large", &models.PDFOptions{Scale: floatPtr(3)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,4 +4,17 @@ type RequestBody struct {
 	Code   string `json:"code"`
 	Format string `json:"format"`
 	Data   any    `json:"data"`
+	Strict bool   `json:"strict,omitempty"`
+
+	// StrictVariables overrides the service default for failing on template
+	// variables missing from Data.
+	StrictVariables *bool `json:"strictVariables,omitempty"`
+
+	PDF *PDFOptions `json:"pdfOptions,omitempty"`
+
+	// Engine overrides the manifest engine, e.g. to compare the two outputs.
+	Engine string `json:"engine,omitempty"`
+
+	CallbackURL    string `json:"callbackUrl,omitempty"`
+	CallbackInline bool   `json:"callbackInline,omitempty"`
 }
@@ -1,6 +1,31 @@
 package models
 
+import "time"
+
 type Template struct {
 	Name   string `json:"name"`
 	Format string `json:"format"`
+
+	Title   map[string]string `json:"title,omitempty"`
+	Owner   string            `json:"owner,omitempty"`
+	Formats []string          `json:"formats,omitempty"`
+	// Outputs are the formats the template's code can be generated in.
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+// TemplateOutput is a format a code can be generated in, the type of
+// template file it is generated from and the media type it is sent as.
+type TemplateOutput struct {
+	Source      string `json:"source"`
+	Format      string `json:"format"`
+	ContentType string `json:"contentType"`
+}
+
+// TemplateStatus describes a template file held by the template registry.
+type TemplateStatus struct {
+	Name        string     `json:"name"`
+	Reloads     int        `json:"reloads"`
+	LoadedAt    *time.Time `json:"loadedAt,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
 }
@@ -0,0 +1,8 @@
+package models
+
+// Violation is one way request data breaks the schema of its template.
+type Violation struct {
+	Path     string `json:"path"`
+	Expected string `json:"expected,omitempty"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type WebhookPayload struct {
+	JobID       string    `json:"jobId"`
+	Status      JobStatus `json:"status"`
+	Code        string    `json:"code"`
+	Format      string    `json:"format"`
+	Error       string    `json:"error,omitempty"`
+	Checksum    string    `json:"checksum,omitempty"`
+	Filename    string    `json:"filename,omitempty"`
+	DownloadURL string    `json:"downloadUrl,omitempty"`
+	Content     string    `json:"content,omitempty"`
+}
+
+type WebhookDelivery struct {
+	Attempt    int       `json:"attempt"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
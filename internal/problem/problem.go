@@ -0,0 +1,67 @@
+// Package problem writes error responses as RFC 7807 problem details, with a
+// stable code clients can match on and the ID of the request that failed.
+package problem
+
+import (
+	"RBKproject4/internal/reqctx"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// Codes identify a kind of problem. They are part of the API and must not
+// change once released.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeTemplateNotFound    = "template_not_found"
+	CodeJobNotFound         = "job_not_found"
+	CodeJobNotReady         = "job_not_ready"
+	CodeInvalidData         = "invalid_data"
+	CodeUndefinedVariables  = "undefined_variables"
+	CodeUnsupportedFormat   = "unsupported_format"
+	CodeUnknownEngine       = "unknown_engine"
+	CodeNotAcceptable       = "not_acceptable"
+	CodeBatchTooLarge       = "batch_too_large"
+	CodeQueueFull           = "queue_full"
+	CodeRenderFailed        = "render_failed"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeUpstreamTimeout     = "upstream_timeout"
+	CodeCircuitOpen         = "upstream_circuit_open"
+	CodeInternal            = "internal_error"
+)
+
+// Write answers with a problem of the given status and code. extensions are
+// added as members next to the standard ones, e.g. the violations of invalid
+// data.
+func Write(c *gin.Context, status int, code, detail string, extensions map[string]any) {
+	body := gin.H{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   status,
+		"code":     code,
+		"instance": c.Request.URL.Path,
+	}
+	if detail != "" {
+		body["detail"] = detail
+	}
+	if id := reqctx.RequestID(c.Request.Context()); id != "" {
+		body["requestId"] = id
+	}
+	for name, value := range extensions {
+		body[name] = value
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.JSON(status, body)
+}
+
+// Abort writes a problem and stops the handler chain.
+func Abort(c *gin.Context, status int, code, detail string) {
+	Write(c, status, code, detail, nil)
+	c.Abort()
+}
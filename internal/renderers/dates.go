@@ -0,0 +1,146 @@
+package renderers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	// templates name IANA zones, which a slim container may not ship
+	_ "time/tzdata"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// dateLayouts are the date and time formats accepted as filter input. Values
+// without a zone are taken as UTC.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+type dateNames struct {
+	months      [12]string
+	monthsOf    [12]string // after a day: 5 января
+	shortMonths [12]string
+	weekdays    [7]string
+	shortDays   [7]string
+}
+
+var dateLocales = map[string]dateNames{
+	"ru": {
+		months:      [12]string{"январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"},
+		monthsOf:    [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths: [12]string{"янв", "фев", "мар", "апр", "мая", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"},
+		weekdays:    [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		shortDays:   [7]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+	},
+	"kk": {
+		months:      [12]string{"қаңтар", "ақпан", "наурыз", "сәуір", "мамыр", "маусым", "шілде", "тамыз", "қыркүйек", "қазан", "қараша", "желтоқсан"},
+		monthsOf:    [12]string{"қаңтар", "ақпан", "наурыз", "сәуір", "мамыр", "маусым", "шілде", "тамыз", "қыркүйек", "қазан", "қараша", "желтоқсан"},
+		shortMonths: [12]string{"қаң", "ақп", "нау", "сәу", "мам", "мау", "шіл", "там", "қыр", "қаз", "қар", "жел"},
+		weekdays:    [7]string{"жексенбі", "дүйсенбі", "сейсенбі", "сәрсенбі", "бейсенбі", "жұма", "сенбі"},
+		shortDays:   [7]string{"жс", "дс", "сс", "ср", "бс", "жм", "сб"},
+	},
+}
+
+// Layout elements are swapped for placeholders before formatting so the
+// localised names can't be mistaken for layout or literal text.
+const (
+	monthPlaceholder      = "\x00M\x00"
+	shortMonthPlaceholder = "\x00m\x00"
+	weekdayPlaceholder    = "\x00W\x00"
+	shortDayPlaceholder   = "\x00w\x00"
+)
+
+// dayInLayout finds a day of the month element once the year is taken out.
+var dayInLayout = regexp.MustCompile(`(^|[^0-9])(_2|02|2)([^0-9]|$)`)
+
+func parseTime(in *pongo2.Value) (time.Time, error) {
+	if t, ok := in.Interface().(time.Time); ok {
+		return t, nil
+	}
+
+	s := strings.TrimSpace(in.String())
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", s)
+}
+
+// filterTimezone converts a date to the IANA zone given as parameter, e.g.
+// "Asia/Almaty", for format_date or pongo2's date filter to format.
+func filterTimezone(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	t, err := parseTime(in)
+	if err != nil {
+		return nil, filterError("timezone", err)
+	}
+	loc, err := time.LoadLocation(param.String())
+	if err != nil {
+		return nil, filterError("timezone", err)
+	}
+	return pongo2.AsValue(t.In(loc)), nil
+}
+
+// filterFormatDate formats a date with a Go layout, optionally prefixed with a
+// locale whose month and weekday names replace the English ones:
+// "ru:2 January 2006" gives "5 января 2025".
+func filterFormatDate(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	t, err := parseTime(in)
+	if err != nil {
+		return nil, filterError("format_date", err)
+	}
+
+	layout := param.String()
+	if layout == "" {
+		return nil, filterError("format_date", errors.New("a layout is required"))
+	}
+
+	names, hasLocale := dateNames{}, false
+	if locale, rest, ok := strings.Cut(layout, ":"); ok {
+		if names, hasLocale = dateLocales[locale]; hasLocale {
+			layout = rest
+		} else if locale == "en" {
+			layout = rest
+		}
+	}
+	if !hasLocale {
+		return pongo2.AsValue(t.Format(layout)), nil
+	}
+
+	months := names.months
+	if dayInLayout.MatchString(strings.ReplaceAll(layout, "2006", "")) {
+		months = names.monthsOf
+	}
+
+	layout = strings.NewReplacer(
+		"January", monthPlaceholder,
+		"Jan", shortMonthPlaceholder,
+		"Monday", weekdayPlaceholder,
+		"Mon", shortDayPlaceholder,
+	).Replace(layout)
+
+	out := strings.NewReplacer(
+		monthPlaceholder, months[t.Month()-1],
+		shortMonthPlaceholder, names.shortMonths[t.Month()-1],
+		weekdayPlaceholder, names.weekdays[t.Weekday()],
+		shortDayPlaceholder, names.shortDays[t.Weekday()],
+	).Replace(t.Format(layout))
+	return pongo2.AsValue(out), nil
+}
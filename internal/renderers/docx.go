@@ -0,0 +1,345 @@
+package renderers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// DocxRenderer renders DOCX templates written for docxtpl in-process, without
+// the Python service. It supports:
+//
+//   - {{ var }} anywhere in the text of the body, headers and footers, also
+//     when Word has split the tag over several runs;
+//   - {%tr for %}...{%tr endfor %} to repeat table rows, and likewise {%tc %}
+//     for cells, {%p %} for paragraphs and {%r %} for runs: the tag replaces
+//     the whole element it is written in, so {%p if x %} ... {%p endif %} shows
+//     or hides paragraphs;
+//   - images whose alt text is {{ var }}: the picture is replaced by var, a
+//     base64 PNG, JPEG or GIF (a data URI works too), keeping the size of the
+//     placeholder. Images are substituted once per document, not per loop
+//     iteration.
+type DocxRenderer struct {
+	cache officeCache[*docxTemplate]
+}
+
+func NewDocxRenderer(templateDir string) *DocxRenderer {
+	return &DocxRenderer{cache: officeCache[*docxTemplate]{templateDir: templateDir, ext: "docx", compile: compileDocx}}
+}
+
+// NewCachedDocxRenderer reads templates from registry.
+func NewCachedDocxRenderer(registry *Registry) *DocxRenderer {
+	r := NewDocxRenderer(registry.Dir())
+	r.cache.registry = registry
+	return r
+}
+
+// CacheStats returns the lookups of compiled templates: misses compiled a
+// template that was new or had changed.
+func (r *DocxRenderer) CacheStats() *CacheStats {
+	return &r.cache.stats
+}
+
+type docxTemplate struct {
+	parts map[string]*docxPart
+}
+
+type docxPart struct {
+	template *pongo2.Template
+	images   []docxImage
+}
+
+// docxImage is a picture whose alt text names the variable to replace it with.
+type docxImage struct {
+	key   string // context key that holds the relationship id to embed
+	value *pongo2.Template
+	relID string // the placeholder picture
+}
+
+var (
+	docxTextParts = regexp.MustCompile(`^word/(?:document|header\d*|footer\d*)\.xml$`)
+
+	// tags between a brace and the rest of a delimiter, left when Word splits
+	// "{{" or "%}" over two runs
+	splitOpenDelimiter  = regexp.MustCompile(`\{(?:<[^>]*>)+([{%#])`)
+	splitCloseDelimiter = regexp.MustCompile(`([}%#])(?:<[^>]*>)+\}`)
+
+	docxDrawing     = regexp.MustCompile(`(?s)<w:drawing>.*?</w:drawing>`)
+	docxImageDescr  = regexp.MustCompile(`descr="\{\{\s*(.*?)\s*\}\}"`)
+	docxImageEmbed  = regexp.MustCompile(`r:embed="([^"]*)"`)
+	docxPlainTextEl = regexp.MustCompile(`<w:t>`)
+	smartQuotes     = strings.NewReplacer("‘", "'", "’", "'", "“", `"`, "”", `"`)
+)
+
+func compileDocx(source []byte) (*docxTemplate, error) {
+	parts, err := readZipParts(source, docxTextParts.MatchString)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl := &docxTemplate{parts: map[string]*docxPart{}}
+	for name, data := range parts {
+		part := &docxPart{}
+		xml := prepareDocxXML(string(data))
+
+		xml, err = part.prepareImages(name, xml)
+		if err != nil {
+			return nil, err
+		}
+
+		part.template, err = compileString(xml)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		tpl.parts[name] = part
+	}
+	return tpl, nil
+}
+
+// prepareDocxXML turns the XML of a part into a pongo2 template.
+func prepareDocxXML(xml string) string {
+	xml = splitOpenDelimiter.ReplaceAllString(xml, "{$1")
+	xml = splitCloseDelimiter.ReplaceAllString(xml, "$1}")
+	xml = cleanTemplateTags(xml)
+
+	for _, element := range []string{"tr", "tc", "p", "r"} {
+		xml = expandElementTags(xml, element)
+	}
+	xml = strings.NewReplacer("{{r ", "{{ ", "{{p ", "{{ ").Replace(xml)
+
+	// values may start or end with spaces, which Word drops unless told not to
+	return docxPlainTextEl.ReplaceAllString(xml, `<w:t xml:space="preserve">`)
+}
+
+// cleanTemplateTags removes the markup Word leaves inside template tags that
+// span several runs, and turns the entities and typographic quotes in them
+// back into the characters pongo2 expects.
+func cleanTemplateTags(xml string) string {
+	var b strings.Builder
+	for {
+		start := indexAny(xml, "{{", "{%", "{#")
+		if start < 0 {
+			b.WriteString(xml)
+			return b.String()
+		}
+
+		closing := map[byte]string{'{': "}}", '%': "%}", '#': "#}"}[xml[start+1]]
+		end := strings.Index(xml[start+2:], closing)
+		if end < 0 {
+			b.WriteString(xml)
+			return b.String()
+		}
+		end += start + 2 + len(closing)
+
+		tag := xmlTags.ReplaceAllString(xml[start:end], "")
+		b.WriteString(xml[:start])
+		b.WriteString(smartQuotes.Replace(html.UnescapeString(tag)))
+		xml = xml[end:]
+	}
+}
+
+func indexAny(s string, subs ...string) int {
+	first := -1
+	for _, sub := range subs {
+		if i := strings.Index(s, sub); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// expandElementTags replaces every <w:element> holding a {%element ... %} tag
+// with the plain {% ... %} tag.
+func expandElementTags(xml, element string) string {
+	marker := "{%" + element + " "
+	open, openAttrs, closeTag := "<w:"+element+">", "<w:"+element+" ", "</w:"+element+">"
+
+	for {
+		pos := strings.Index(xml, marker)
+		if pos < 0 {
+			return xml
+		}
+		tagEnd := strings.Index(xml[pos:], "%}")
+		if tagEnd < 0 {
+			return xml
+		}
+		statement := strings.TrimSpace(xml[pos+len(marker) : pos+tagEnd])
+
+		start := max(strings.LastIndex(xml[:pos], open), strings.LastIndex(xml[:pos], openAttrs))
+		end := strings.Index(xml[pos:], closeTag)
+		if start < 0 || end < 0 {
+			// not inside such an element: keep the tag as a plain one
+			xml = xml[:pos] + "{% " + statement + " %}" + xml[pos+tagEnd+2:]
+			continue
+		}
+		end += pos + len(closeTag)
+
+		xml = xml[:start] + "{% " + statement + " %}" + xml[end:]
+	}
+}
+
+// prepareImages finds the pictures whose alt text is a template tag and makes
+// their relationship id a template variable.
+func (p *docxPart) prepareImages(partName, xml string) (string, error) {
+	var err error
+	xml = docxDrawing.ReplaceAllStringFunc(xml, func(drawing string) string {
+		descr := docxImageDescr.FindStringSubmatch(drawing)
+		embed := docxImageEmbed.FindStringSubmatch(drawing)
+		if descr == nil || embed == nil || err != nil {
+			return drawing
+		}
+
+		expr := smartQuotes.Replace(html.UnescapeString(descr[1]))
+		value, compileErr := compileString("{{ " + expr + "|safe }}")
+		if compileErr != nil {
+			err = fmt.Errorf("%s: image %q: %w", partName, expr, compileErr)
+			return drawing
+		}
+
+		image := docxImage{
+			key:   fmt.Sprintf("docxImage%d", len(p.images)),
+			value: value,
+			relID: embed[1],
+		}
+		p.images = append(p.images, image)
+
+		drawing = docxImageDescr.ReplaceAllString(drawing, `descr=""`)
+		return docxImageEmbed.ReplaceAllString(drawing, `r:embed="{{ `+image.key+` }}"`)
+	})
+	return xml, err
+}
+
+// Render renders the DOCX template templateName with data.
+func (r *DocxRenderer) Render(templateName string, data map[string]interface{}) ([]byte, error) {
+	out, _, err := r.RenderSource(templateName, data)
+	return out, err
+}
+
+// RenderSource renders the DOCX template templateName with data, and returns
+// the template file it was rendered from as well.
+func (r *DocxRenderer) RenderSource(templateName string, data map[string]interface{}) ([]byte, []byte, error) {
+	tpl, source, err := r.cache.get(templateName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := readZipParts(source, func(name string) bool {
+		return name == "[Content_Types].xml" || strings.HasPrefix(name, "word/_rels/")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing := map[string]bool{}
+	for name := range files {
+		existing[name] = true
+	}
+
+	replaced := map[string][]byte{}
+	added := map[string][]byte{}
+	for name, part := range tpl.parts {
+		ctx := pongo2.Context{}
+		for k, v := range data {
+			ctx[k] = v
+		}
+
+		rels := relsName(name)
+		for i, image := range part.images {
+			ctx[image.key] = image.relID
+
+			encoded, err := image.value.Execute(ctx)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: image %d: %w", name, i+1, err)
+			}
+			if strings.TrimSpace(encoded) == "" {
+				continue
+			}
+
+			picture, ext, err := decodeImage(encoded)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: image %d: %w", name, i+1, err)
+			}
+
+			media := fmt.Sprintf("media/%s_image%d.%s", strings.TrimSuffix(path.Base(name), ".xml"), i+1, ext)
+			relID := fmt.Sprintf("rIdTemplateImage%d", i+1)
+			added["word/"+media] = picture
+			files[rels] = addRelationship(files[rels], relID, media)
+			files["[Content_Types].xml"] = addDefaultContentType(files["[Content_Types].xml"], ext)
+			ctx[image.key] = relID
+		}
+
+		out, err := part.template.Execute(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		replaced[name] = []byte(out)
+	}
+
+	// relationships and content types only change when an image was replaced
+	if len(added) > 0 {
+		for name, data := range files {
+			if existing[name] {
+				replaced[name] = data
+			} else {
+				added[name] = data
+			}
+		}
+	}
+
+	out, err := rewriteZip(source, replaced, added)
+	return out, source, err
+}
+
+// relsName returns the relationships part of a part: word/_rels/document.xml.rels.
+func relsName(part string) string {
+	return path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+}
+
+var imageExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+}
+
+func decodeImage(encoded string) ([]byte, string, error) {
+	encoded = strings.TrimSpace(encoded)
+	if _, rest, ok := strings.Cut(encoded, ";base64,"); ok && strings.HasPrefix(encoded, "data:") {
+		encoded = rest
+	}
+
+	picture, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("image is not valid base64: %w", err)
+	}
+
+	ext, ok := imageExtensions[http.DetectContentType(picture)]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported image type %s", http.DetectContentType(picture))
+	}
+	return picture, ext, nil
+}
+
+const imageRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+
+func addRelationship(rels []byte, id, target string) []byte {
+	if rels == nil {
+		rels = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`)
+	}
+	rel := fmt.Sprintf(`<Relationship Id="%s" Type="%s" Target="%s"/>`, id, imageRelationshipType, target)
+	return []byte(strings.Replace(string(rels), "</Relationships>", rel+"</Relationships>", 1))
+}
+
+func addDefaultContentType(types []byte, ext string) []byte {
+	if strings.Contains(strings.ToLower(string(types)), `extension="`+ext+`"`) {
+		return types
+	}
+	def := fmt.Sprintf(`<Default Extension="%s" ContentType="image/%s"/>`, ext, ext)
+	return []byte(strings.Replace(string(types), "</Types>", def+"</Types>", 1))
+}
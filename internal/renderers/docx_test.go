@@ -0,0 +1,190 @@
+package renderers_test
+
+import (
+	"RBKproject4/internal/renderers"
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// onePixelPNG is a valid 1x1 PNG.
+const onePixelPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+const docxBody = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><w:body>` +
+	// a tag Word has split over three runs, with the braces apart
+	`<w:p><w:r><w:t>Client: {</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>{ client</w:t></w:r><w:r><w:t>Name }} &amp; co</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>{%p if vip %}</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>VIP client</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>{%p endif %}</w:t></w:r></w:p>` +
+	`<w:tbl>` +
+	`<w:tr><w:tc><w:p><w:r><w:t>Amount</w:t></w:r></w:p></w:tc></w:tr>` +
+	`<w:tr><w:tc><w:p><w:r><w:t>{%tr for t in transactions %}</w:t></w:r></w:p></w:tc></w:tr>` +
+	`<w:tr><w:trPr/><w:tc><w:p><w:r><w:t>{{ t.amount|amount:“KZT” }}</w:t></w:r></w:p></w:tc></w:tr>` +
+	`<w:tr><w:tc><w:p><w:r><w:t>{%tr endfor %}</w:t></w:r></w:p></w:tc></w:tr>` +
+	`</w:tbl>` +
+	`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Picture 1" descr="{{ logo }}"/><a:graphic><a:blip r:embed="rId5"/></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+	`</w:body></w:document>`
+
+func buildDocx(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(b)
+	}
+	return parts
+}
+
+var docxMarkup = regexp.MustCompile(`<[^>]*>`)
+
+func docxText(t *testing.T, part string) string {
+	t.Helper()
+	assertWellFormed(t, part)
+	return docxMarkup.ReplaceAllString(part, "|")
+}
+
+func assertWellFormed(t *testing.T, part string) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(part))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("output is not well-formed XML: %v\n%s", err, part)
+		}
+	}
+}
+
+func newDocxFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	docx := buildDocx(t, map[string]string{
+		"[Content_Types].xml":          `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/></Types>`,
+		"word/document.xml":            docxBody,
+		"word/header1.xml":             `<w:hdr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p><w:r><w:t>{{ bank }}</w:t></w:r></w:p></w:hdr>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/></Relationships>`,
+		"word/media/image1.png":        "placeholder",
+	})
+	if err := os.WriteFile(filepath.Join(dir, "PDP.docx"), docx, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDocxRenderer_Render(t *testing.T) {
+	r := renderers.NewDocxRenderer(newDocxFixture(t))
+
+	out, err := r.Render("PDP", map[string]interface{}{
+		"clientName":   "Aigerim <Ltd>",
+		"bank":         "Bank RBK",
+		"vip":          false,
+		"transactions": []interface{}{map[string]interface{}{"amount": 1500}, map[string]interface{}{"amount": 20.5}},
+		"logo":         "data:image/png;base64," + onePixelPNG,
+	})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	parts := readParts(t, out)
+	text := docxText(t, parts["word/document.xml"])
+
+	for _, want := range []string{"Client: Aigerim &lt;Ltd&gt; &amp; co", "|1 500,00 ₸|", "|20,50 ₸|", "|Amount|"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("document missing %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"VIP client", "{%", "{{", "for t in"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("document contains %q:\n%s", unwanted, text)
+		}
+	}
+	if got := strings.Count(parts["word/document.xml"], "<w:tr>"); got != 3 {
+		t.Errorf("table has %d rows, want header and two transactions", got)
+	}
+
+	if header := docxText(t, parts["word/header1.xml"]); !strings.Contains(header, "Bank RBK") {
+		t.Errorf("header = %s", header)
+	}
+
+	t.Run("image", func(t *testing.T) {
+		if !strings.Contains(parts["word/document.xml"], `r:embed="rIdTemplateImage1"`) {
+			t.Fatalf("image relationship not replaced:\n%s", parts["word/document.xml"])
+		}
+		if !strings.Contains(parts["word/_rels/document.xml.rels"], `Id="rIdTemplateImage1"`) {
+			t.Errorf("relationship missing: %s", parts["word/_rels/document.xml.rels"])
+		}
+		png, _ := base64.StdEncoding.DecodeString(onePixelPNG)
+		if parts["word/media/document_image1.png"] != string(png) {
+			t.Errorf("media not added")
+		}
+		if !strings.Contains(parts["[Content_Types].xml"], `Extension="png"`) {
+			t.Errorf("png content type missing: %s", parts["[Content_Types].xml"])
+		}
+	})
+}
+
+func TestDocxRenderer_KeepsPlaceholderImage(t *testing.T) {
+	r := renderers.NewDocxRenderer(newDocxFixture(t))
+
+	out, err := r.Render("PDP", map[string]interface{}{"vip": true})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	parts := readParts(t, out)
+	if !strings.Contains(parts["word/document.xml"], `r:embed="rId5"`) {
+		t.Errorf("placeholder image not kept")
+	}
+	if !strings.Contains(docxText(t, parts["word/document.xml"]), "VIP client") {
+		t.Errorf("conditional paragraph missing")
+	}
+	if _, ok := parts["word/media/document_image1.png"]; ok {
+		t.Errorf("unexpected media added")
+	}
+}
+
+func TestDocxRenderer_MissingTemplate(t *testing.T) {
+	r := renderers.NewDocxRenderer(t.TempDir())
+	if _, err := r.Render("missing", nil); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
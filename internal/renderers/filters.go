@@ -0,0 +1,216 @@
+package renderers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// The filters below let templates format raw values themselves instead of
+// every client sending pre-formatted strings:
+//
+//	{{ amount|amount:"KZT" }}            1 234 567,89 ₸
+//	{{ amount|amount_words:"ru:USD" }}   Одна тысяча долларов США
+//	{{ date|timezone:"Asia/Almaty"|format_date:"ru:2 January 2006" }}
+//	{{ iban|iban }}                      KZ86 125K ZT50 0410 0100
+//	{{ pan|mask_card }}                  4400 43** **** 1234
+//
+// Empty or missing values render as an empty string, like any other missing
+// variable.
+func init() {
+	pongo2.RegisterFilter("amount", filterAmount)
+	pongo2.RegisterFilter("amount_words", filterAmountWords)
+	pongo2.RegisterFilter("timezone", filterTimezone)
+	pongo2.RegisterFilter("format_date", filterFormatDate)
+	pongo2.RegisterFilter("iban", filterIBAN)
+	pongo2.RegisterFilter("mask_card", filterMaskCard)
+}
+
+// currencySymbols are written after the amount, as is usual in Kazakhstan.
+var currencySymbols = map[string]string{
+	"KZT": "₸",
+	"USD": "$",
+	"EUR": "€",
+	"RUB": "₽",
+}
+
+var decimalPattern = regexp.MustCompile(`^([-+]?)(\d+)(?:\.(\d+))?$`)
+
+// decimal is an amount rounded to two decimal places.
+type decimal struct {
+	negative bool
+	units    string // integer part, without leading zeros
+	cents    string // always two digits
+}
+
+func filterError(name string, err error) *pongo2.Error {
+	return &pongo2.Error{Sender: "filter:" + name, OrigError: err}
+}
+
+func isEmpty(in *pongo2.Value) bool {
+	return in.IsNil() || (in.IsString() && strings.TrimSpace(in.String()) == "")
+}
+
+// parseDecimal reads a number or a numeric string such as "1 234,5" and
+// rounds it half up to two decimal places. Strings are parsed as written so
+// large amounts don't go through float64.
+func parseDecimal(in *pongo2.Value) (decimal, error) {
+	var s string
+	switch {
+	case in.IsInteger():
+		s = strconv.Itoa(in.Integer())
+	case in.IsFloat():
+		s = strconv.FormatFloat(in.Float(), 'f', -1, 64)
+	default:
+		s = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(strings.TrimSpace(in.String()))
+	}
+
+	m := decimalPattern.FindStringSubmatch(s)
+	if m == nil {
+		return decimal{}, fmt.Errorf("%q is not a number", in.String())
+	}
+
+	frac := m[3] + "000"
+	n, _ := new(big.Int).SetString(m[2]+frac[:2], 10)
+	if frac[2] >= '5' {
+		n.Add(n, big.NewInt(1))
+	}
+
+	digits := n.String()
+	if len(digits) < 3 {
+		digits = strings.Repeat("0", 3-len(digits)) + digits
+	}
+	d := decimal{
+		units: strings.TrimLeft(digits[:len(digits)-2], "0"),
+		cents: digits[len(digits)-2:],
+	}
+	if d.units == "" {
+		d.units = "0"
+	}
+	d.negative = m[1] == "-" && (d.units != "0" || d.cents != "00")
+	return d, nil
+}
+
+// groupThousands inserts a space between every three digits from the right.
+func groupThousands(digits string) string {
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// filterAmount formats a number as "1 234 567,89", followed by the symbol of
+// the currency given as parameter. Currencies without a symbol keep their code.
+func filterAmount(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	d, err := parseDecimal(in)
+	if err != nil {
+		return nil, filterError("amount", err)
+	}
+
+	out := groupThousands(d.units) + "," + d.cents
+	if d.negative {
+		out = "-" + out
+	}
+
+	if currency := strings.ToUpper(strings.TrimSpace(param.String())); currency != "" {
+		if symbol, ok := currencySymbols[currency]; ok {
+			currency = symbol
+		}
+		out += " " + currency
+	}
+	return pongo2.AsValue(out), nil
+}
+
+// filterAmountWords spells the integer part of an amount in the language given
+// as parameter, optionally followed by a currency: "ru", "kk:KZT", "en:USD".
+// With a currency the name agrees with the number, and non-zero cents are
+// added in digits: "Одна тысяча долларов США 50 центов".
+func filterAmountWords(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	lang, currency, _ := strings.Cut(param.String(), ":")
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		lang = "ru"
+	}
+
+	d, err := parseDecimal(in)
+	if err != nil {
+		return nil, filterError("amount_words", err)
+	}
+
+	out, err := amountInWords(d, lang, strings.ToUpper(strings.TrimSpace(currency)))
+	if err != nil {
+		return nil, filterError("amount_words", err)
+	}
+	return pongo2.AsValue(out), nil
+}
+
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]{1,30}$`)
+
+// filterIBAN writes an IBAN in groups of four characters.
+func filterIBAN(in *pongo2.Value, _ *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	iban := strings.ToUpper(strings.Join(strings.Fields(in.String()), ""))
+	if !ibanPattern.MatchString(iban) {
+		return nil, filterError("iban", fmt.Errorf("%q is not an IBAN", in.String()))
+	}
+	return pongo2.AsValue(groupBy(iban, 4)), nil
+}
+
+// filterMaskCard keeps the first six and last four digits of a card number,
+// which PCI DSS allows to be shown, and masks the rest. The first character of
+// the parameter replaces the default "*" mask.
+func filterMaskCard(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if isEmpty(in) {
+		return pongo2.AsValue(""), nil
+	}
+
+	pan := strings.NewReplacer(" ", "", "-", "").Replace(in.String())
+	for _, c := range pan {
+		if c < '0' || c > '9' {
+			return nil, filterError("mask_card", errors.New("card number must contain digits only"))
+		}
+	}
+	if len(pan) < 12 || len(pan) > 19 {
+		return nil, filterError("mask_card", fmt.Errorf("card number must have 12 to 19 digits, got %d", len(pan)))
+	}
+
+	mask := "*"
+	if r := []rune(param.String()); !param.IsNil() && len(r) > 0 {
+		mask = string(r[0])
+	}
+
+	masked := pan[:6] + strings.Repeat(mask, len(pan)-10) + pan[len(pan)-4:]
+	return pongo2.AsValue(groupBy(masked, 4)), nil
+}
+
+func groupBy(s string, size int) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && i%size == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
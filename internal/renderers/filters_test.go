@@ -0,0 +1,132 @@
+package renderers_test
+
+import (
+	"RBKproject4/internal/renderers"
+	"testing"
+	"time"
+)
+
+type filterCase struct {
+	name     string
+	template string
+	value    interface{}
+	want     string
+	wantErr  bool
+}
+
+func runFilterCases(t *testing.T, tests []filterCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderers.RenderString(tt.template, map[string]interface{}{"v": tt.value})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAmount(t *testing.T) {
+	runFilterCases(t, []filterCase{
+		{"integer", `{{ v|amount }}`, 1234567, "1 234 567,00", false},
+		{"float", `{{ v|amount }}`, 1234.5, "1 234,50", false},
+		{"rounds half up", `{{ v|amount }}`, "0.125", "0,13", false},
+		{"rounding carries", `{{ v|amount }}`, "999.995", "1 000,00", false},
+		{"small", `{{ v|amount }}`, 0.07, "0,07", false},
+		{"negative", `{{ v|amount:"KZT" }}`, -1500, "-1 500,00 ₸", false},
+		{"negative rounding to zero", `{{ v|amount }}`, "-0.001", "0,00", false},
+		{"formatted string", `{{ v|amount }}`, "1 234,5", "1 234,50", false},
+		{"large string keeps precision", `{{ v|amount }}`, "12345678901234567.89", "12 345 678 901 234 567,89", false},
+		{"KZT", `{{ v|amount:"KZT" }}`, 1000, "1 000,00 ₸", false},
+		{"USD", `{{ v|amount:"usd" }}`, 1000, "1 000,00 $", false},
+		{"EUR", `{{ v|amount:"EUR" }}`, 1000, "1 000,00 €", false},
+		{"RUB", `{{ v|amount:"RUB" }}`, 1000, "1 000,00 ₽", false},
+		{"other currency keeps code", `{{ v|amount:"CHF" }}`, 1000, "1 000,00 CHF", false},
+		{"empty", `{{ v|amount:"KZT" }}`, "", "", false},
+		{"missing", `{{ missing|amount }}`, nil, "", false},
+		{"not a number", `{{ v|amount }}`, "abc", "", true},
+	})
+}
+
+func TestFilterAmountWords(t *testing.T) {
+	runFilterCases(t, []filterCase{
+		{"ru number", `{{ v|amount_words:"ru" }}`, 1234, "Одна тысяча двести тридцать четыре", false},
+		{"ru default language", `{{ v|amount_words }}`, 21, "Двадцать один", false},
+		{"ru zero", `{{ v|amount_words:"ru:KZT" }}`, 0, "Ноль тенге", false},
+		{"ru USD example", `{{ v|amount_words:"ru:USD" }}`, 1000, "Одна тысяча долларов США", false},
+		{"ru thousands few", `{{ v|amount_words:"ru:RUB" }}`, 2002, "Две тысячи два рубля", false},
+		{"ru thousands many", `{{ v|amount_words:"ru:RUB" }}`, 5011, "Пять тысяч одиннадцать рублей", false},
+		{"ru eleven thousand", `{{ v|amount_words:"ru:RUB" }}`, 11000, "Одиннадцать тысяч рублей", false},
+		{"ru twenty one thousand", `{{ v|amount_words:"ru:RUB" }}`, 21001, "Двадцать одна тысяча один рубль", false},
+		{"ru millions", `{{ v|amount_words:"ru:KZT" }}`, 3000000, "Три миллиона тенге", false},
+		{"ru billions", `{{ v|amount_words:"ru" }}`, 1000000001, "Один миллиард один", false},
+		{"ru cents feminine", `{{ v|amount_words:"ru:RUB" }}`, 1.01, "Один рубль 01 копейка", false},
+		{"ru cents few", `{{ v|amount_words:"ru:RUB" }}`, 2.22, "Два рубля 22 копейки", false},
+		{"ru cents", `{{ v|amount_words:"ru:USD" }}`, 1000.5, "Одна тысяча долларов США 50 центов", false},
+		{"ru EUR", `{{ v|amount_words:"ru:EUR" }}`, 101, "Сто один евро", false},
+		{"ru negative", `{{ v|amount_words:"ru" }}`, -5, "Минус пять", false},
+		{"kk number", `{{ v|amount_words:"kk" }}`, 1234, "Бір мың екі жүз отыз төрт", false},
+		{"kk KZT", `{{ v|amount_words:"kk:KZT" }}`, 150000.75, "Бір жүз елу мың теңге 75 тиын", false},
+		{"kk USD", `{{ v|amount_words:"kk:USD" }}`, 2, "Екі АҚШ доллары", false},
+		{"kk zero", `{{ v|amount_words:"kk" }}`, 0, "Нөл", false},
+		{"en number", `{{ v|amount_words:"en" }}`, 1234, "One thousand two hundred thirty-four", false},
+		{"en singular", `{{ v|amount_words:"en:USD" }}`, 1.01, "One US dollar 01 cent", false},
+		{"en plural", `{{ v|amount_words:"en:EUR" }}`, 40, "Forty euros", false},
+		{"en millions", `{{ v|amount_words:"en:KZT" }}`, 2000015, "Two million fifteen tenge", false},
+		{"unknown language", `{{ v|amount_words:"de" }}`, 1, "", true},
+		{"unknown currency", `{{ v|amount_words:"ru:CHF" }}`, 1, "", true},
+		{"too large", `{{ v|amount_words:"ru" }}`, "1000000000000000", "", true},
+		{"empty", `{{ v|amount_words:"ru" }}`, "", "", false},
+	})
+}
+
+func TestFilterDates(t *testing.T) {
+	runFilterCases(t, []filterCase{
+		{"ru genitive month", `{{ v|format_date:"ru:2 January 2006" }}`, "2025-01-05", "5 января 2025", false},
+		{"ru nominative month", `{{ v|format_date:"ru:January 2006" }}`, "2025-03-01", "март 2025", false},
+		{"ru weekday", `{{ v|format_date:"ru:Monday, 02.01.2006" }}`, "2025-01-06", "понедельник, 06.01.2025", false},
+		{"ru short", `{{ v|format_date:"ru:02 Jan 2006" }}`, "2025-05-09", "09 мая 2025", false},
+		{"kk month", `{{ v|format_date:"kk:2006 ж. 2 January" }}`, "2025-09-01", "2025 ж. 1 қыркүйек", false},
+		{"en keeps names", `{{ v|format_date:"en:2 January 2006" }}`, "2025-01-05", "5 January 2025", false},
+		{"layout only", `{{ v|format_date:"02.01.2006 15:04" }}`, "2025-01-05T10:30:00Z", "05.01.2025 10:30", false},
+		{"dotted input", `{{ v|format_date:"2006-01-02" }}`, "05.01.2025", "2025-01-05", false},
+		{"time value", `{{ v|format_date:"ru:2 January" }}`, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), "31 декабря", false},
+		{"timezone", `{{ v|timezone:"Asia/Almaty"|format_date:"02.01.2006 15:04" }}`, "2025-01-05T20:30:00Z", "06.01.2025 01:30", false},
+		{"timezone with offset input", `{{ v|timezone:"UTC"|format_date:"15:04" }}`, "2025-01-05T10:00:00+05:00", "05:00", false},
+		{"unknown timezone", `{{ v|timezone:"Mars/Olympus" }}`, "2025-01-05", "", true},
+		{"not a date", `{{ v|format_date:"2006" }}`, "yesterday", "", true},
+		{"missing layout", `{{ v|format_date }}`, "2025-01-05", "", true},
+		{"empty", `{{ v|format_date:"2006" }}`, "", "", false},
+	})
+}
+
+func TestFilterIBAN(t *testing.T) {
+	runFilterCases(t, []filterCase{
+		{"groups", `{{ v|iban }}`, "KZ86125KZT5004100100", "KZ86 125K ZT50 0410 0100", false},
+		{"normalises", `{{ v|iban }}`, "kz86 125k zt50 0410 0100", "KZ86 125K ZT50 0410 0100", false},
+		{"odd length", `{{ v|iban }}`, "DE89370400440532013000", "DE89 3704 0044 0532 0130 00", false},
+		{"invalid", `{{ v|iban }}`, "12345", "", true},
+		{"empty", `{{ v|iban }}`, "", "", false},
+	})
+}
+
+func TestFilterMaskCard(t *testing.T) {
+	runFilterCases(t, []filterCase{
+		{"16 digits", `{{ v|mask_card }}`, "4400430012341234", "4400 43** **** 1234", false},
+		{"with separators", `{{ v|mask_card }}`, "4400-4300-1234-1234", "4400 43** **** 1234", false},
+		{"custom mask", `{{ v|mask_card:"•" }}`, "4400430012341234", "4400 43•• •••• 1234", false},
+		{"19 digits", `{{ v|mask_card }}`, "6200000000000000001", "6200 00** **** ***0 001", false},
+		{"too short", `{{ v|mask_card }}`, "12345", "", true},
+		{"letters", `{{ v|mask_card }}`, "4400 43ab 1234 1234", "", true},
+		{"empty", `{{ v|mask_card }}`, "", "", false},
+	})
+}
@@ -3,3 +3,21 @@ package renderers
 type TemplateRenderer interface {
 	Render(templateName string, data map[string]interface{}) (string, error)
 }
+
+// SourceRenderer is implemented by template renderers that can return the
+// template source a render used, e.g. to record which version of a template
+// a document was made from.
+type SourceRenderer interface {
+	RenderSource(templateName string, data map[string]interface{}) (string, []byte, error)
+}
+
+// DocumentRenderer renders an office template, such as a DOCX, into the bytes
+// of the finished document.
+type DocumentRenderer interface {
+	Render(templateName string, data map[string]interface{}) ([]byte, error)
+}
+
+// SourceDocumentRenderer is the SourceRenderer of office templates.
+type SourceDocumentRenderer interface {
+	RenderSource(templateName string, data map[string]interface{}) ([]byte, []byte, error)
+}
@@ -0,0 +1,121 @@
+package renderers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// lookupRecorder collects the undefined paths a render looked up.
+type lookupRecorder struct {
+	seen map[string]bool
+}
+
+func (r *lookupRecorder) paths() []string {
+	paths := make([]string, 0, len(r.seen))
+	for p := range r.seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// trackLookups returns a copy of data in which each of paths, as reported by
+// the analyzer, holds a function that records the lookup in rec. pongo2 calls
+// functions it finds in the data when it resolves a variable, and the function
+// resolves to nothing, as the missing value would.
+//
+// data itself is not changed. Paths that can't hold a function are skipped:
+// indexes past the end of a list, and keys of empty objects, which would no
+// longer test as false.
+func trackLookups(data map[string]interface{}, paths []string, rec *lookupRecorder) map[string]interface{} {
+	tracked := make(map[string]interface{}, len(data)+len(paths))
+	for k, v := range data {
+		tracked[k] = v
+	}
+	for _, p := range paths {
+		parts := lookupParts(p)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) == 1 {
+			tracked[parts[0]] = recordLookup(p, rec)
+			continue
+		}
+		tracked[parts[0]] = withLookup(tracked[parts[0]], parts[1:], recordLookup(p, rec))
+	}
+	return tracked
+}
+
+func recordLookup(path string, rec *lookupRecorder) func() *pongo2.Value {
+	return func() *pongo2.Value {
+		rec.seen[path] = true
+		return pongo2.AsValue(nil)
+	}
+}
+
+// lookupParts splits a path such as "transactions[].fee" or "items[2].note"
+// into "transactions", "[]", "fee".
+func lookupParts(path string) []string {
+	var parts []string
+	for _, segment := range strings.Split(path, ".") {
+		name, index, _ := strings.Cut(segment, "[")
+		if name != "" {
+			parts = append(parts, name)
+		}
+		if index != "" {
+			parts = append(parts, "["+index)
+		}
+	}
+	return parts
+}
+
+// withLookup returns v with fn set at parts, copying what it changes.
+func withLookup(v interface{}, parts []string, fn func() *pongo2.Value) interface{} {
+	part := parts[0]
+	switch c := v.(type) {
+	case map[string]interface{}:
+		if strings.HasPrefix(part, "[") {
+			return v
+		}
+		item, ok := c[part]
+		switch {
+		case len(parts) == 1 && (ok || len(c) == 0):
+			return v
+		case len(parts) > 1 && !ok:
+			return v
+		}
+		copied := make(map[string]interface{}, len(c)+1)
+		for k, item := range c {
+			copied[k] = item
+		}
+		if len(parts) == 1 {
+			copied[part] = fn
+		} else {
+			copied[part] = withLookup(item, parts[1:], fn)
+		}
+		return copied
+	case []interface{}:
+		if len(parts) == 1 || !strings.HasPrefix(part, "[") {
+			return v
+		}
+		copied := make([]interface{}, len(c))
+		copy(copied, c)
+		if part == "[]" {
+			for i := range copied {
+				copied[i] = withLookup(copied[i], parts[1:], fn)
+			}
+			return copied
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(part, "["), "]"))
+		if err != nil || index < 0 || index >= len(c) {
+			return v
+		}
+		copied[index] = withLookup(copied[index], parts[1:], fn)
+		return copied
+	default:
+		return v
+	}
+}
@@ -0,0 +1,197 @@
+package renderers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// officeTextParts are the parts of a DOCX or XLSX package that hold text a
+	// template tag can appear in.
+	officeTextParts = regexp.MustCompile(`^(?:word/(?:document|header\d*|footer\d*|footnotes|endnotes)\.xml|xl/sharedStrings\.xml|xl/worksheets/sheet\d+\.xml)$`)
+
+	// officeBreaks end a paragraph, shared string or cell, so tags on either
+	// side of them don't run together.
+	officeBreaks = regexp.MustCompile(`</w:p>|</si>|</c>`)
+	xmlTags      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// OfficeText extracts the text of a DOCX or XLSX template so its tags can be
+// checked with UndefinedVariables. Word splits text into runs freely, so the
+// markup between runs is dropped to put split tags back together.
+func OfficeText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open office document: %w", err)
+	}
+
+	var text strings.Builder
+	for _, f := range zr.File {
+		if !officeTextParts.MatchString(f.Name) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+
+		part := officeBreaks.ReplaceAllString(string(content), "\n")
+		text.WriteString(html.UnescapeString(xmlTags.ReplaceAllString(part, "")))
+		text.WriteString("\n")
+	}
+	return text.String(), nil
+}
+
+// officeCache loads office templates from disk, or from a Registry when there
+// is one, and keeps what an engine compiled from each until the file changes.
+type officeCache[T any] struct {
+	templateDir string
+	registry    *Registry
+	ext         string
+	compile     func(source []byte) (T, error)
+
+	mu      sync.Mutex
+	entries map[string]officeCacheEntry[T]
+	stats   CacheStats
+}
+
+type officeCacheEntry[T any] struct {
+	sum      [sha256.Size]byte
+	compiled T
+}
+
+// get returns the compiled template of templateName and its source.
+func (c *officeCache[T]) get(templateName string) (T, []byte, error) {
+	var zero T
+
+	name := templateName + "." + c.ext
+	var source []byte
+	var err error
+	if c.registry != nil {
+		source, err = c.registry.Source(name)
+	} else {
+		source, err = os.ReadFile(filepath.Join(c.templateDir, name))
+	}
+	if err != nil {
+		return zero, nil, err
+	}
+
+	sum := sha256.Sum256(source)
+	c.mu.Lock()
+	entry, ok := c.entries[templateName]
+	c.mu.Unlock()
+	hit := ok && entry.sum == sum
+	c.stats.record(hit)
+	if hit {
+		return entry.compiled, source, nil
+	}
+
+	compiled, err := c.compile(source)
+	if err != nil {
+		return zero, nil, fmt.Errorf("failed to compile template %s: %w", name, err)
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]officeCacheEntry[T]{}
+	}
+	c.entries[templateName] = officeCacheEntry[T]{sum: sum, compiled: compiled}
+	c.mu.Unlock()
+	return compiled, source, nil
+}
+
+// rewriteZip copies the package source, replacing the parts in replaced and
+// appending the ones in added. A nil part in replaced is dropped.
+func rewriteZip(source []byte, replaced, added map[string][]byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(source), int64(len(source)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open office document: %w", err)
+	}
+
+	out := &bytes.Buffer{}
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		data, ok := replaced[f.Name]
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return nil, fmt.Errorf("failed to copy %s: %w", f.Name, err)
+			}
+			continue
+		}
+		if data == nil {
+			continue
+		}
+		if err := writeZipPart(zw, f.Name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(added))
+	for name := range added {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeZipPart(zw, name, added[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish office document: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func writeZipPart(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// readZipParts returns the contents of the parts of source that match.
+func readZipParts(source []byte, match func(name string) bool) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(source), int64(len(source)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open office document: %w", err)
+	}
+
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		if !match(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		parts[f.Name] = data
+	}
+	return parts, nil
+}
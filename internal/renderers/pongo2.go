@@ -1,21 +1,115 @@
 package renderers
 
 import (
+	"os"
+	"sync"
+
 	"github.com/flosch/pongo2/v6"
 )
 
+// pongo2's TemplateSet writes shared state without locking whenever a template
+// is loaded, so loads into the default set have to take turns.
+var loadMu sync.Mutex
+
 type Pongo2Renderer struct {
 	templateDir string
+	registry    *Registry
 }
 
 func NewPongo2Renderer(templateDir string) *Pongo2Renderer {
 	return &Pongo2Renderer{templateDir: templateDir}
 }
 
+// NewCachedPongo2Renderer renders the templates compiled by registry instead
+// of parsing the file on every call.
+func NewCachedPongo2Renderer(registry *Registry) *Pongo2Renderer {
+	return &Pongo2Renderer{templateDir: registry.Dir(), registry: registry}
+}
+
 func (r *Pongo2Renderer) Render(templateName string, data map[string]interface{}) (string, error) {
+	out, _, err := r.RenderSource(templateName, data)
+	return out, err
+}
+
+// RenderSource renders templateName with data, and returns the template file
+// it was rendered from as well. With a registry that is the source the
+// compiled template was made of, whatever has been reloaded since; without
+// one the file is read again right before it is compiled.
+func (r *Pongo2Renderer) RenderSource(templateName string, data map[string]interface{}) (string, []byte, error) {
+	if r.registry != nil {
+		tpl, source, err := r.registry.TemplateSource(templateName + ".html")
+		if err != nil {
+			return "", nil, err
+		}
+		out, err := tpl.Execute(data)
+		return out, source, err
+	}
+
+	source, err := os.ReadFile(r.templateDir + "/" + templateName + ".html")
+	if err != nil {
+		return "", nil, err
+	}
+	loadMu.Lock()
 	tpl, err := pongo2.FromFile(r.templateDir + "/" + templateName + ".html")
+	loadMu.Unlock()
+	if err != nil {
+		return "", nil, err
+	}
+	out, err := tpl.Execute(data)
+	return out, source, err
+}
+
+// RenderString renders an inline template such as a filename pattern.
+func RenderString(source string, data map[string]interface{}) (string, error) {
+	tpl, err := compileString(source)
 	if err != nil {
 		return "", err
 	}
 	return tpl.Execute(data)
 }
+
+func compileString(source string) (*pongo2.Template, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	return pongo2.FromString(source)
+}
+
+// UndefinedVariables reports the variables templateName uses that data does
+// not define.
+func (r *Pongo2Renderer) UndefinedVariables(templateName string, data map[string]interface{}) ([]string, error) {
+	source, err := r.source(templateName)
+	if err != nil {
+		return nil, err
+	}
+	return UndefinedVariables(string(source), data), nil
+}
+
+// UndefinedLookups renders templateName with data and reports the variables
+// data does not define that the render looked up outside of an if or a
+// default filter. Unlike UndefinedVariables it is not misled by guards it
+// doesn't understand, variables set by a base template or branches that
+// weren't taken. Only the variables UndefinedVariables finds are tracked, so
+// ones that only included templates use are not reported.
+func (r *Pongo2Renderer) UndefinedLookups(templateName string, data map[string]interface{}) ([]string, error) {
+	source, err := r.source(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := lookupCandidates(string(source), data)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	rec := &lookupRecorder{seen: map[string]bool{}}
+	if _, err := r.Render(templateName, trackLookups(data, candidates, rec)); err != nil {
+		return nil, err
+	}
+	return rec.paths(), nil
+}
+
+func (r *Pongo2Renderer) source(templateName string) ([]byte, error) {
+	if r.registry != nil {
+		return r.registry.Source(templateName + ".html")
+	}
+	return os.ReadFile(r.templateDir + "/" + templateName + ".html")
+}
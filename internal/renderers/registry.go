@@ -0,0 +1,362 @@
+package renderers
+
+import (
+	"RBKproject4/internal/models"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/fsnotify/fsnotify"
+)
+
+// registryExtensions are the template files the registry keeps in memory.
+var registryExtensions = map[string]bool{".html": true, ".docx": true, ".xlsx": true}
+
+// reloadDelay lets a burst of events for one file settle before it is read, so
+// that a save which truncates the file first isn't loaded half written.
+const reloadDelay = 100 * time.Millisecond
+
+// Registry keeps every template of a directory in memory: HTML templates are
+// compiled once, DOCX and XLSX templates are kept as bytes. Once Watch is
+// running, edits, additions and deletions in the directory are picked up
+// without a restart. An edit that fails to load keeps serving the last good
+// version and is reported through Status.
+type Registry struct {
+	dir    string
+	logger *slog.Logger
+	set    *pongo2.TemplateSet
+
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	stats   CacheStats
+
+	// the template set is not safe for concurrent compiles
+	compileMu sync.Mutex
+
+	watcher   *fsnotify.Watcher
+	done      chan struct{}
+	pendingMu sync.Mutex
+	pending   map[string]*time.Timer
+}
+
+type registryEntry struct {
+	source   []byte
+	compiled *pongo2.Template
+
+	reloads     int
+	loadedAt    time.Time
+	lastError   error
+	lastErrorAt time.Time
+}
+
+// NewRegistry loads every template in dir.
+func NewRegistry(dir string, logger *slog.Logger) (*Registry, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	loader, err := pongo2.NewLocalFileSystemLoader(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template loader: %w", err)
+	}
+
+	r := &Registry{
+		dir:     dir,
+		logger:  logger,
+		set:     pongo2.NewSet("templates", loader),
+		entries: map[string]*registryEntry{},
+		pending: map[string]*time.Timer{},
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template dir: %w", err)
+	}
+	for _, f := range files {
+		if !f.IsDir() && registryExtensions[filepath.Ext(f.Name())] {
+			r.load(f.Name())
+		}
+	}
+	return r, nil
+}
+
+// Dir is the directory the registry loads templates from.
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+// Template returns the compiled HTML template stored in name, e.g. "PDP.html".
+func (r *Registry) Template(name string) (*pongo2.Template, error) {
+	tpl, _, err := r.TemplateSource(name)
+	return tpl, err
+}
+
+// TemplateSource returns the compiled HTML template stored in name together
+// with the source it was compiled from.
+func (r *Registry) TemplateSource(name string) (*pongo2.Template, []byte, error) {
+	e, err := r.entry(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.compiled == nil {
+		return nil, nil, e.lastError
+	}
+	return e.compiled, e.source, nil
+}
+
+// Source returns the contents of the template file name as last loaded.
+func (r *Registry) Source(name string) ([]byte, error) {
+	e, err := r.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	if e.source == nil {
+		return nil, e.lastError
+	}
+	return e.source, nil
+}
+
+func (r *Registry) entry(name string) (*registryEntry, error) {
+	r.mu.RLock()
+	e, ok := r.entries[name]
+	r.mu.RUnlock()
+	r.stats.record(ok)
+	if ok {
+		return e, nil
+	}
+
+	// names come from requests, and must not reach outside the directory
+	if !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("template %s: %w", name, os.ErrNotExist)
+	}
+	// a file created before the watcher noticed it, or in a subdirectory
+	if _, err := os.Stat(filepath.Join(r.dir, name)); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	r.load(name)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.entries[name], nil
+}
+
+// load reads and compiles name. On failure the previous version, if any, is
+// kept and the error recorded next to it.
+func (r *Registry) load(name string) {
+	source, err := os.ReadFile(filepath.Join(r.dir, name))
+
+	var compiled *pongo2.Template
+	if err == nil && filepath.Ext(name) == ".html" {
+		r.compileMu.Lock()
+		compiled, err = r.set.FromBytes(source)
+		r.compileMu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// entries are read without holding the lock, so they're replaced rather
+	// than updated in place
+	next := &registryEntry{}
+	if e, ok := r.entries[name]; ok {
+		*next = *e
+	}
+
+	now := time.Now().UTC()
+	if err != nil {
+		next.lastError = err
+		next.lastErrorAt = now
+		r.entries[name] = next
+		r.logger.Warn("failed to load template, keeping last good version", "template", name, "error", err)
+		return
+	}
+
+	if next.source != nil {
+		next.reloads++
+	}
+	next.source = source
+	next.compiled = compiled
+	next.loadedAt = now
+	next.lastError = nil
+	next.lastErrorAt = time.Time{}
+	r.entries[name] = next
+}
+
+func (r *Registry) remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, name)
+}
+
+// Watch starts reloading templates as files in the directory change. It
+// returns once the watcher is set up; Close stops it.
+func (r *Registry) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create template watcher: %w", err)
+	}
+	if err := watcher.Add(r.dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch template dir: %w", err)
+	}
+
+	r.watcher = watcher
+	r.done = make(chan struct{})
+	go r.watch()
+	return nil
+}
+
+func (r *Registry) watch() {
+	defer close(r.done)
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			r.schedule(event)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.logger.Error("template watcher failed", "error", err)
+		}
+	}
+}
+
+func (r *Registry) schedule(event fsnotify.Event) {
+	name := filepath.Base(event.Name)
+	if !registryExtensions[filepath.Ext(name)] || event.Op == fsnotify.Chmod {
+		return
+	}
+
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	if r.pending == nil {
+		return
+	}
+	if timer, ok := r.pending[name]; ok {
+		timer.Reset(reloadDelay)
+		return
+	}
+	r.pending[name] = time.AfterFunc(reloadDelay, func() {
+		r.pendingMu.Lock()
+		delete(r.pending, name)
+		r.pendingMu.Unlock()
+		r.reload(name)
+	})
+}
+
+// reload brings name in line with the file on disk once its events settled.
+func (r *Registry) reload(name string) {
+	if _, err := os.Stat(filepath.Join(r.dir, name)); errors.Is(err, fs.ErrNotExist) {
+		r.logger.Info("template removed", "template", name)
+		r.remove(name)
+	} else {
+		r.logger.Info("reloading template", "template", name)
+		r.load(name)
+	}
+
+	if filepath.Ext(name) == ".html" {
+		r.reloadDependents(name)
+	}
+}
+
+// reloadDependents recompiles the HTML templates that include or extend name,
+// since pongo2 resolves those references at compile time.
+func (r *Registry) reloadDependents(name string) {
+	r.mu.RLock()
+	var dependents []string
+	for other, e := range r.entries {
+		if other != name && e.compiled != nil && strings.Contains(string(e.source), name) {
+			dependents = append(dependents, other)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, other := range dependents {
+		r.load(other)
+	}
+}
+
+// Close stops watching the directory.
+func (r *Registry) Close() error {
+	if r.watcher == nil {
+		return nil
+	}
+	err := r.watcher.Close()
+	<-r.done
+
+	r.pendingMu.Lock()
+	for _, timer := range r.pending {
+		timer.Stop()
+	}
+	r.pending = nil
+	r.pendingMu.Unlock()
+	return err
+}
+
+// Status reports, per template file, how often it has been reloaded and the
+// last error loading it, if the current file on disk failed to load.
+func (r *Registry) Status() []models.TemplateStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]models.TemplateStatus, 0, len(r.entries))
+	for name, e := range r.entries {
+		status := models.TemplateStatus{
+			Name:    name,
+			Reloads: e.reloads,
+		}
+		if e.source != nil {
+			loadedAt := e.loadedAt
+			status.LoadedAt = &loadedAt
+		}
+		if e.lastError != nil {
+			lastErrorAt := e.lastErrorAt
+			status.LastError = e.lastError.Error()
+			status.LastErrorAt = &lastErrorAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// CacheStats returns the lookups of the registry: hits were served from
+// memory, misses had to read the file first.
+func (r *Registry) CacheStats() *CacheStats {
+	return &r.stats
+}
+
+// CacheStats counts the lookups of a template cache that found what they
+// needed and those that had to load or compile it.
+type CacheStats struct {
+	hits, misses atomic.Uint64
+}
+
+func (s *CacheStats) Hits() uint64 {
+	return s.hits.Load()
+}
+
+func (s *CacheStats) Misses() uint64 {
+	return s.misses.Load()
+}
+
+func (s *CacheStats) record(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
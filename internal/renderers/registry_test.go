@@ -0,0 +1,140 @@
+package renderers_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually polls cond until it holds or the watcher had plenty of time.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func statusOf(r *renderers.Registry, name string) *models.TemplateStatus {
+	for _, s := range r.Status() {
+		if s.Name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+func TestRegistry_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "greet.html", "Hello {{ name }}!")
+	writeTemplate(t, dir, "PDP.docx", "docx v1")
+
+	registry, err := renderers.NewRegistry(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	if err := registry.Watch(); err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer registry.Close()
+
+	r := renderers.NewCachedPongo2Renderer(registry)
+	render := func() string {
+		out, err := r.Render("greet", map[string]interface{}{"name": "World"})
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		return out
+	}
+
+	if out := render(); out != "Hello World!" {
+		t.Fatalf("Render() = %q", out)
+	}
+
+	t.Run("edit", func(t *testing.T) {
+		writeTemplate(t, dir, "greet.html", "Hi {{ name }}!")
+		eventually(t, "edit to be picked up", func() bool { return render() == "Hi World!" })
+
+		if s := statusOf(registry, "greet.html"); s == nil || s.Reloads == 0 {
+			t.Errorf("status = %+v, want a reload counted", s)
+		}
+	})
+
+	t.Run("broken edit keeps last good version", func(t *testing.T) {
+		writeTemplate(t, dir, "greet.html", "Hi {{ name ")
+		eventually(t, "error to be recorded", func() bool {
+			s := statusOf(registry, "greet.html")
+			return s != nil && s.LastError != ""
+		})
+
+		if out := render(); out != "Hi World!" {
+			t.Errorf("Render() = %q, want the last good version", out)
+		}
+	})
+
+	t.Run("addition", func(t *testing.T) {
+		writeTemplate(t, dir, "bye.html", "Bye {{ name }}")
+		eventually(t, "new template", func() bool { return statusOf(registry, "bye.html") != nil })
+
+		out, err := r.Render("bye", map[string]interface{}{"name": "World"})
+		if err != nil || out != "Bye World" {
+			t.Errorf("Render() = %q, %v", out, err)
+		}
+	})
+
+	t.Run("office source", func(t *testing.T) {
+		writeTemplate(t, dir, "PDP.docx", "docx v2")
+		eventually(t, "docx reload", func() bool {
+			src, err := registry.Source("PDP.docx")
+			return err == nil && string(src) == "docx v2"
+		})
+	})
+
+	t.Run("deletion", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(dir, "bye.html")); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, "removal", func() bool { return statusOf(registry, "bye.html") == nil })
+
+		if _, err := r.Render("bye", nil); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Render() error = %v, want fs.ErrNotExist", err)
+		}
+	})
+}
+
+func TestRegistry_ReloadsIncludingTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "base.html", "[{% block body %}{% endblock %}]")
+	writeTemplate(t, dir, "page.html", `{% extends "base.html" %}{% block body %}page{% endblock %}`)
+
+	registry, err := renderers.NewRegistry(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	if err := registry.Watch(); err != nil {
+		t.Fatalf("Watch() error: %v", err)
+	}
+	defer registry.Close()
+
+	r := renderers.NewCachedPongo2Renderer(registry)
+	writeTemplate(t, dir, "base.html", "<{% block body %}{% endblock %}>")
+	eventually(t, "base change to reach page", func() bool {
+		out, err := r.Render("page", nil)
+		return err == nil && out == "<page>"
+	})
+}
@@ -0,0 +1,439 @@
+package renderers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UndefinedVariablesError is returned in strict mode when a template uses
+// variables that the data does not define.
+type UndefinedVariablesError struct {
+	Template string
+	Paths    []string
+}
+
+func (e *UndefinedVariablesError) Error() string {
+	return fmt.Sprintf("template %s uses undefined variables: %s", e.Template, strings.Join(e.Paths, ", "))
+}
+
+// VariableChecker is implemented by renderers that can report the variables a
+// template uses but the data leaves undefined.
+type VariableChecker interface {
+	// UndefinedVariables lists them from the template's source, see
+	// UndefinedVariables.
+	UndefinedVariables(templateName string, data map[string]interface{}) ([]string, error)
+	// UndefinedLookups renders the template and lists the ones the render
+	// actually looked up.
+	UndefinedLookups(templateName string, data map[string]interface{}) ([]string, error)
+}
+
+var (
+	commentPattern      = regexp.MustCompile(`(?s)\{#.*?#\}|\{%-?\s*comment\s*-?%\}.*?\{%-?\s*endcomment\s*-?%\}`)
+	tagPattern          = regexp.MustCompile(`(?s)\{\{-?(.*?)-?\}\}|\{%-?(.*?)-?%\}`)
+	officeTagPrefix     = regexp.MustCompile(`^(?:tr|tc|p|r)\s+`)
+	exprTokenPattern    = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_]+|\[[^\]]*\])*|\d+(?:\.\d+)?|\|\s*[A-Za-z_][A-Za-z0-9_]*|==|!=|<=|>=|&&|\|\||\S`)
+	pathPartPattern     = regexp.MustCompile(`[A-Za-z0-9_]+|\[[^\]]*\]`)
+	withAssignPattern   = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(\S+)`)
+	macroSignatureRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*\(([^)]*)\)`)
+)
+
+var exprKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "is": true, "as": true,
+	"true": true, "false": true, "True": true, "False": true, "None": true, "nil": true,
+	"reversed": true, "sorted": true, "with": true, "only": true,
+}
+
+// optionalFilters make the variable they are applied to optional.
+var optionalFilters = map[string]bool{"default": true, "default_if_none": true}
+
+// UndefinedVariables lists the variable paths that source refers to and data
+// does not define, e.g. "clientIin" or "transactions[].amount".
+//
+// This is a static pass over the template's tags, used for warnings. It
+// follows for, with, set and macro scopes, treats variables filtered through
+// default as optional and does not report paths that are guarded by an
+// enclosing if on the same variable. Variables that are present with a null
+// value count as defined.
+//
+// Only source itself is read: include, import and extends are not followed,
+// so variables used only in those templates are not reported, and variables a
+// base template sets are reported as missing. Guards other than an if on the
+// same path or a parent of it, e.g. an if on a sibling field, are not
+// understood either. Strict mode therefore doesn't fail on this list but on
+// the lookups a render actually makes, see Pongo2Renderer.UndefinedLookups.
+func UndefinedVariables(source string, data map[string]interface{}) []string {
+	return sortedPaths(analyze(source, data).missing)
+}
+
+// lookupCandidates are the paths of UndefinedVariables whose lookup during a
+// render means that the template used them. Paths that are also tested, by an
+// if or a default filter, are left out: a render looks those up without using
+// them.
+func lookupCandidates(source string, data map[string]interface{}) []string {
+	a := analyze(source, data)
+	for p := range a.tested {
+		delete(a.missing, p)
+	}
+	return sortedPaths(a.missing)
+}
+
+func analyze(source string, data map[string]interface{}) *analyzer {
+	a := &analyzer{
+		data:    data,
+		scopes:  []map[string]binding{{}},
+		macros:  map[string]bool{},
+		missing: map[string]bool{},
+		tested:  map[string]bool{},
+	}
+
+	source = commentPattern.ReplaceAllString(source, "")
+	for _, m := range tagPattern.FindAllStringSubmatch(source, -1) {
+		if strings.HasPrefix(m[0], "{{") {
+			a.output(officeTagPrefix.ReplaceAllString(strings.TrimSpace(m[1]), ""))
+		} else {
+			a.tag(officeTagPrefix.ReplaceAllString(strings.TrimSpace(m[2]), ""))
+		}
+	}
+	return a
+}
+
+func sortedPaths(set map[string]bool) []string {
+	paths := make([]string, 0, len(set))
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// binding is a name introduced by a tag. values holds what the name may take
+// (every element for a loop variable); opaque bindings are always defined.
+type binding struct {
+	origin string
+	values []any
+	opaque bool
+}
+
+type analyzer struct {
+	data    map[string]interface{}
+	scopes  []map[string]binding
+	guards  [][]string
+	macros  map[string]bool
+	missing map[string]bool
+	// tested are the missing paths that an if or a default filter looks up
+	tested map[string]bool
+}
+
+type reference struct {
+	path     string
+	optional bool
+}
+
+func (a *analyzer) output(expr string) {
+	for _, ref := range a.references(expr) {
+		if ref.optional {
+			a.test(ref.path)
+		} else {
+			a.check(ref.path)
+		}
+	}
+}
+
+// test records path as looked up without being used, if it is missing.
+func (a *analyzer) test(path string) {
+	if _, _, missing := a.resolve(path); missing != "" {
+		a.tested[missing] = true
+	}
+}
+
+func (a *analyzer) tag(body string) {
+	name, args, _ := strings.Cut(body, " ")
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "for":
+		a.forTag(args)
+	case "endfor", "endwith", "endmacro":
+		a.popScope()
+	case "if", "ifequal", "ifnotequal":
+		a.guards = append(a.guards, a.guardPaths(args))
+	case "elif":
+		if len(a.guards) > 0 {
+			top := len(a.guards) - 1
+			a.guards[top] = append(a.guards[top], a.guardPaths(args)...)
+		}
+	case "endif", "endifequal", "endifnotequal":
+		if len(a.guards) > 0 {
+			a.guards = a.guards[:len(a.guards)-1]
+		}
+	case "with":
+		a.withTag(args)
+	case "set":
+		if variable, expr, ok := strings.Cut(args, "="); ok {
+			a.output(expr)
+			a.scopes[len(a.scopes)-1][strings.TrimSpace(variable)] = binding{opaque: true}
+		}
+	case "macro":
+		a.macroTag(args)
+	case "firstof":
+		// firstof exists to pick whichever variable is defined
+		for _, ref := range a.references(args) {
+			a.test(ref.path)
+		}
+	case "cycle", "widthratio", "filter":
+		a.output(args)
+	}
+}
+
+func (a *analyzer) forTag(args string) {
+	vars, expr, ok := strings.Cut(args, " in ")
+	if !ok {
+		return
+	}
+
+	scope := map[string]binding{}
+	names := strings.Split(vars, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+		scope[names[i]] = binding{opaque: true}
+	}
+
+	refs := a.references(expr)
+	if len(refs) > 0 {
+		iterable := refs[0].path
+		if values, origin, missing := a.resolve(iterable); missing != "" {
+			if refs[0].optional {
+				a.tested[missing] = true
+			} else {
+				a.report(iterable, missing)
+			}
+		} else {
+			var items []any
+			for _, v := range values {
+				switch c := v.(type) {
+				case []any:
+					items = append(items, c...)
+				case map[string]any:
+					for _, item := range c {
+						items = append(items, item)
+					}
+				}
+			}
+
+			// in "for key, value in m" only the value can be navigated further
+			scope[names[len(names)-1]] = binding{origin: origin + "[]", values: items}
+		}
+	}
+
+	a.scopes = append(a.scopes, scope)
+}
+
+func (a *analyzer) withTag(args string) {
+	scope := map[string]binding{}
+
+	if expr, name, ok := strings.Cut(args, " as "); ok {
+		scope[strings.TrimSpace(name)] = a.bind(expr)
+	} else {
+		for _, m := range withAssignPattern.FindAllStringSubmatch(args, -1) {
+			scope[m[1]] = a.bind(m[2])
+		}
+	}
+
+	a.scopes = append(a.scopes, scope)
+}
+
+func (a *analyzer) macroTag(args string) {
+	m := macroSignatureRegex.FindStringSubmatch(args)
+	if m == nil {
+		a.scopes = append(a.scopes, map[string]binding{})
+		return
+	}
+
+	a.macros[m[1]] = true
+	scope := map[string]binding{}
+	for _, arg := range strings.Split(m[2], ",") {
+		name, _, _ := strings.Cut(arg, "=")
+		if name = strings.TrimSpace(name); name != "" {
+			scope[name] = binding{opaque: true}
+		}
+	}
+	a.scopes = append(a.scopes, scope)
+}
+
+// bind resolves expr for a with-style assignment, reporting it when missing.
+func (a *analyzer) bind(expr string) binding {
+	refs := a.references(expr)
+	if len(refs) != 1 {
+		a.output(expr)
+		return binding{opaque: true}
+	}
+
+	values, origin, missing := a.resolve(refs[0].path)
+	if missing != "" {
+		if refs[0].optional {
+			a.tested[missing] = true
+		} else {
+			a.report(refs[0].path, missing)
+		}
+		return binding{opaque: true}
+	}
+	return binding{origin: origin, values: values}
+}
+
+func (a *analyzer) popScope() {
+	// the outermost scope holds top-level set variables and is never popped
+	if len(a.scopes) > 1 {
+		a.scopes = a.scopes[:len(a.scopes)-1]
+	}
+}
+
+// guardPaths returns the paths an if tests, recording them as tested.
+func (a *analyzer) guardPaths(expr string) []string {
+	refs := a.references(expr)
+	paths := make([]string, len(refs))
+	for i, ref := range refs {
+		paths[i] = ref.path
+		a.test(ref.path)
+	}
+	return paths
+}
+
+// references extracts the variable paths used by an expression.
+func (a *analyzer) references(expr string) []reference {
+	tokens := exprTokenPattern.FindAllString(expr, -1)
+
+	var refs []reference
+	for i, tok := range tokens {
+		switch {
+		case tok[0] == '"' || tok[0] == '\'':
+		case tok[0] == '|' && len(tok) > 1:
+			if optionalFilters[strings.TrimSpace(tok[1:])] && len(refs) > 0 && i > 0 && tokens[i-1] == refs[len(refs)-1].path {
+				refs[len(refs)-1].optional = true
+			}
+		case isIdentStart(tok[0]):
+			head := tok
+			if j := strings.IndexAny(head, ".["); j >= 0 {
+				head = head[:j]
+			}
+			if exprKeywords[head] {
+				continue
+			}
+			// function and macro calls
+			if i+1 < len(tokens) && tokens[i+1] == "(" {
+				continue
+			}
+			refs = append(refs, reference{path: tok})
+		}
+	}
+	return refs
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (a *analyzer) check(path string) {
+	if _, _, missing := a.resolve(path); missing != "" {
+		a.report(path, missing)
+	}
+}
+
+// report records missing unless an enclosing if tests path or one of its
+// parents.
+func (a *analyzer) report(path, missing string) {
+	for _, frame := range a.guards {
+		for _, guard := range frame {
+			if path == guard || strings.HasPrefix(path, guard+".") || strings.HasPrefix(path, guard+"[") {
+				return
+			}
+		}
+	}
+	a.missing[missing] = true
+}
+
+// resolve walks path through the scopes and data. It returns the values the
+// path may take and the name to report them under, or the first undefined
+// path.
+func (a *analyzer) resolve(path string) ([]any, string, string) {
+	parts := pathPartPattern.FindAllString(path, -1)
+	if len(parts) == 0 {
+		return nil, "", ""
+	}
+
+	first := parts[0]
+	if first == "forloop" || a.macros[first] {
+		return nil, first, ""
+	}
+
+	var values []any
+	origin := first
+	found := false
+	for i := len(a.scopes) - 1; i >= 0; i-- {
+		if b, ok := a.scopes[i][first]; ok {
+			if b.opaque {
+				return nil, first, ""
+			}
+			values, origin, found = b.values, b.origin, true
+			break
+		}
+	}
+	if !found {
+		v, ok := a.data[first]
+		if !ok {
+			return nil, "", first
+		}
+		values = []any{v}
+	}
+
+	for _, part := range parts[1:] {
+		key := strings.Trim(part, `[]"'`)
+		index, indexErr := strconv.Atoi(key)
+
+		var next []any
+		for _, v := range values {
+			switch c := v.(type) {
+			case nil:
+				// present but null: nothing further to check
+			case map[string]any:
+				item, ok := c[key]
+				if !ok {
+					return nil, "", origin + "." + key
+				}
+				next = append(next, item)
+			case []any:
+				if indexErr == nil {
+					if index < 0 || index >= len(c) {
+						return nil, "", fmt.Sprintf("%s[%d]", origin, index)
+					}
+					next = append(next, c[index])
+					continue
+				}
+				// table.field addresses a field of every row, as in XLSX templates
+				for _, elem := range c {
+					m, ok := elem.(map[string]any)
+					if !ok {
+						continue
+					}
+					item, ok := m[key]
+					if !ok {
+						return nil, "", origin + "[]." + key
+					}
+					next = append(next, item)
+				}
+			default:
+				return nil, "", origin + "." + key
+			}
+		}
+
+		values = next
+		if indexErr == nil {
+			origin = fmt.Sprintf("%s[%d]", origin, index)
+		} else {
+			origin += "." + key
+		}
+	}
+	return values, origin, ""
+}
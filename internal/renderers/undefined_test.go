@@ -0,0 +1,147 @@
+package renderers_test
+
+import (
+	"RBKproject4/internal/renderers"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUndefinedVariables(t *testing.T) {
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+		"clientName": "Aigerim",
+		"account": {"number": "KZ01", "owner": null},
+		"transactions": [{"amount": 10}, {"amount": 20, "note": "x"}],
+		"limits": {"daily": {"value": 1}}
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"all defined", `{{ clientName }} {{ account.number }}`, []string{}},
+		{"missing top level", `{{ clientName }} {{ clientIin }}`, []string{"clientIin"}},
+		{"missing attribute", `{{ account.bic }}`, []string{"account.bic"}},
+		{"null is defined", `{{ account.owner.name }}`, []string{}},
+		{"loop item field", `{% for t in transactions %}{{ t.amount }}{{ t.note }}{% endfor %}`, []string{"transactions[].note"}},
+		{"loop scope ends", `{% for t in transactions %}{% endfor %}{{ t }}`, []string{"t"}},
+		{"missing iterable", `{% for t in payments %}{{ t.amount }}{% endfor %}`, []string{"payments"}},
+		{"forloop", `{% for t in transactions %}{{ forloop.Counter }}{% endfor %}`, []string{}},
+		{"map loop", `{% for k, v in limits %}{{ k }}{{ v.value }}{{ v.max }}{% endfor %}`, []string{"limits[].max"}},
+		{"default filter", `{{ clientIin|default:"-" }} {{ bic|upper }}`, []string{"bic"}},
+		{"if guard", `{% if discount %}{{ discount.amount }}{% endif %}{{ discount.amount }}`, []string{"discount"}},
+		{"with", `{% with acc=account %}{{ acc.number }}{{ acc.iban }}{% endwith %}`, []string{"account.iban"}},
+		{"set", `{% set total = 5 %}{{ total }}`, []string{}},
+		{"macro arguments", `{% macro row(label, value) %}{{ label }}{{ value }}{% endmacro %}{{ row("a", clientName) }}`, []string{}},
+		{"index", `{{ transactions.1.note }} {{ transactions.5.note }}`, []string{"transactions[5]"}},
+		{"literals and keywords", `{% if clientName == "x" and not false %}{{ "text" }}{% endif %}`, []string{}},
+		{"comments", `{# {{ hidden }} #}{% comment %}{{ gone }}{% endcomment %}`, []string{}},
+		{"docxtpl tags", `{%tr for t in transactions %}{{r t.fee }}{%tr endfor %}`, []string{"transactions[].fee"}},
+		{"table attribute", `{{ transactions.amount }} {{ transactions.fee }}`, []string{"transactions[].fee"}},
+		// known limits: other templates are not read
+		{"include not followed", `{% include "part.html" with fee=fee %}`, []string{}},
+		{"base template not read", `{% extends "base.html" %}{% block body %}{{ pageTitle }}{% endblock %}`, []string{"pageTitle"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderers.UndefinedVariables(tt.source, data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UndefinedVariables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfficeText_JoinsSplitRuns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Word often splits a tag across runs
+	if _, err := w.Write([]byte(`<w:document><w:body><w:p><w:r><w:t>{{ client</w:t></w:r><w:r><w:t>Iin }}</w:t></w:r></w:p><w:p><w:r><w:t>&quot;x&quot;</w:t></w:r></w:p></w:body></w:document>`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := renderers.OfficeText(buf.Bytes())
+	if err != nil {
+		t.Fatalf("OfficeText() error: %v", err)
+	}
+
+	got := renderers.UndefinedVariables(text, map[string]interface{}{})
+	if want := []string{"clientIin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UndefinedVariables() = %v, want %v (text %q)", got, want, text)
+	}
+}
+
+func TestPongo2Renderer_UndefinedLookups(t *testing.T) {
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+		"show": true,
+		"hide": false,
+		"account": {"number": "KZ01"},
+		"empty": {},
+		"transactions": [{"amount": 10}, {"amount": 20, "note": "x"}]
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"used", `{{ clientIin }}`, []string{"clientIin"}},
+		{"nested", `{{ account.bic }} {{ payer.name }}`, []string{"account.bic", "payer"}},
+		{"loop item field", `{% for t in transactions %}{{ t.note }}{% endfor %}`, []string{"transactions[].note"}},
+		{"taken branch", `{% if show %}{{ clientIin }}{% endif %}`, []string{"clientIin"}},
+		{"branch not taken", `{% if hide %}{{ clientIin }}{% endif %}`, []string{}},
+		{"sibling guard", `{% if account.iban %}{{ account.bic }}{% endif %}`, []string{}},
+		{"set by base template", `{% extends "base.html" %}{% block body %}{{ company }}{% endblock %}`, []string{}},
+		{"tested elsewhere", `{{ clientIin|default:"-" }}{% if hide %}{{ clientIin }}{% endif %}`, []string{}},
+		{"empty object", `{{ empty.field }}`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "T.html"), []byte(tt.source), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			base := `{% set company = "RBK" %}{% block body %}{% endblock %}`
+			if err := os.WriteFile(filepath.Join(tmpDir, "base.html"), []byte(base), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r := renderers.NewPongo2Renderer(tmpDir)
+
+			got, err := r.UndefinedLookups("T", data)
+			if err != nil {
+				t.Fatalf("UndefinedLookups() error: %v", err)
+			}
+			if got == nil {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UndefinedLookups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the data is copied, not changed
+	if _, ok := data["account"].(map[string]interface{})["bic"]; ok {
+		t.Error("UndefinedLookups() changed the data")
+	}
+}
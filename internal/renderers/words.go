@@ -0,0 +1,267 @@
+package renderers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// currencyNames are the names of a currency and its minor unit in one
+// language, in the plural forms that language needs: one/few/many for
+// Russian, singular/plural for English and a single form for Kazakh, where
+// nouns after a numeral stay singular.
+type currencyNames struct {
+	major []string
+	minor []string
+	// feminine is set when the major unit is grammatically feminine in Russian
+	feminine bool
+}
+
+var amountLanguages = map[string]struct {
+	number     func(n int64, feminine bool) string
+	plural     func(n int64) int
+	minus      string
+	currencies map[string]currencyNames
+}{
+	"ru": {
+		number: ruNumber,
+		plural: ruPlural,
+		minus:  "минус",
+		currencies: map[string]currencyNames{
+			"KZT": {major: []string{"тенге", "тенге", "тенге"}, minor: []string{"тиын", "тиын", "тиын"}},
+			"USD": {major: []string{"доллар США", "доллара США", "долларов США"}, minor: []string{"цент", "цента", "центов"}},
+			"EUR": {major: []string{"евро", "евро", "евро"}, minor: []string{"евроцент", "евроцента", "евроцентов"}},
+			"RUB": {major: []string{"рубль", "рубля", "рублей"}, minor: []string{"копейка", "копейки", "копеек"}},
+		},
+	},
+	"kk": {
+		number: func(n int64, _ bool) string { return kkNumber(n) },
+		plural: func(int64) int { return 0 },
+		minus:  "минус",
+		currencies: map[string]currencyNames{
+			"KZT": {major: []string{"теңге"}, minor: []string{"тиын"}},
+			"USD": {major: []string{"АҚШ доллары"}, minor: []string{"цент"}},
+			"EUR": {major: []string{"еуро"}, minor: []string{"еуроцент"}},
+			"RUB": {major: []string{"Ресей рублі"}, minor: []string{"тиын"}},
+		},
+	},
+	"en": {
+		number: func(n int64, _ bool) string { return enNumber(n) },
+		plural: func(n int64) int {
+			if n == 1 {
+				return 0
+			}
+			return 1
+		},
+		minus: "minus",
+		currencies: map[string]currencyNames{
+			"KZT": {major: []string{"tenge", "tenge"}, minor: []string{"tiyn", "tiyn"}},
+			"USD": {major: []string{"US dollar", "US dollars"}, minor: []string{"cent", "cents"}},
+			"EUR": {major: []string{"euro", "euros"}, minor: []string{"cent", "cents"}},
+			"RUB": {major: []string{"Russian ruble", "Russian rubles"}, minor: []string{"kopeck", "kopecks"}},
+		},
+	},
+}
+
+// maxAmountInWords is the first amount the scale words below can't spell.
+const maxAmountInWords = 1_000_000_000_000_000
+
+func amountInWords(d decimal, lang, currency string) (string, error) {
+	language, ok := amountLanguages[lang]
+	if !ok {
+		return "", fmt.Errorf("unsupported language %q", lang)
+	}
+
+	units, err := strconv.ParseInt(d.units, 10, 64)
+	if err != nil || units >= maxAmountInWords {
+		return "", fmt.Errorf("%s is too large to spell", d.units)
+	}
+
+	var names currencyNames
+	if currency != "" {
+		if names, ok = language.currencies[currency]; !ok {
+			return "", fmt.Errorf("unsupported currency %q", currency)
+		}
+	}
+
+	words := language.number(units, names.feminine)
+	if d.negative {
+		words = language.minus + " " + words
+	}
+	words = capitalize(words)
+	if currency == "" {
+		return words, nil
+	}
+
+	words += " " + names.major[language.plural(units)]
+	if cents, _ := strconv.ParseInt(d.cents, 10, 64); cents != 0 {
+		words += " " + d.cents + " " + names.minor[language.plural(cents)]
+	}
+	return words, nil
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// triples splits n into groups of three digits, least significant first.
+func triples(n int64) []int {
+	var groups []int
+	for n > 0 {
+		groups = append(groups, int(n%1000))
+		n /= 1000
+	}
+	return groups
+}
+
+var (
+	ruOnes        = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	ruOnesFem     = []string{"", "одна", "две"}
+	ruTeens       = []string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	ruTens        = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	ruHundreds    = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+	ruScaleNames  = [][]string{{"тысяча", "тысячи", "тысяч"}, {"миллион", "миллиона", "миллионов"}, {"миллиард", "миллиарда", "миллиардов"}, {"триллион", "триллиона", "триллионов"}}
+	ruScaleGender = []bool{true, false, false, false}
+)
+
+// ruPlural picks the form of a noun after n: 1 рубль, 2 рубля, 5 рублей.
+func ruPlural(n int64) int {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return 2
+	case n%10 == 1:
+		return 0
+	case n%10 >= 2 && n%10 <= 4:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// ruNumber spells n in Russian. feminine makes one and two agree with a
+// feminine noun (одна копейка, две тысячи).
+func ruNumber(n int64, feminine bool) string {
+	if n == 0 {
+		return "ноль"
+	}
+
+	groups := triples(n)
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+
+		fem := feminine
+		if i > 0 {
+			fem = ruScaleGender[i-1]
+		}
+		words = append(words, ruTriple(g, fem)...)
+		if i > 0 {
+			words = append(words, ruScaleNames[i-1][ruPlural(int64(g))])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func ruTriple(n int, feminine bool) []string {
+	var words []string
+	if n >= 100 {
+		words = append(words, ruHundreds[n/100])
+	}
+
+	switch rest := n % 100; {
+	case rest >= 10 && rest < 20:
+		words = append(words, ruTeens[rest-10])
+	default:
+		if rest >= 20 {
+			words = append(words, ruTens[rest/10])
+		}
+		if ones := rest % 10; ones > 0 {
+			if feminine && ones <= 2 {
+				words = append(words, ruOnesFem[ones])
+			} else {
+				words = append(words, ruOnes[ones])
+			}
+		}
+	}
+	return words
+}
+
+var (
+	kkOnes   = []string{"", "бір", "екі", "үш", "төрт", "бес", "алты", "жеті", "сегіз", "тоғыз"}
+	kkTens   = []string{"", "он", "жиырма", "отыз", "қырық", "елу", "алпыс", "жетпіс", "сексен", "тоқсан"}
+	kkScales = []string{"мың", "миллион", "миллиард", "триллион"}
+)
+
+// kkNumber spells n in Kazakh. Hundreds and thousands are always preceded by
+// their count (бір жүз, бір мың), as financial documents write them.
+func kkNumber(n int64) string {
+	if n == 0 {
+		return "нөл"
+	}
+
+	groups := triples(n)
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+
+		if g >= 100 {
+			words = append(words, kkOnes[g/100], "жүз")
+		}
+		if tens := g % 100 / 10; tens > 0 {
+			words = append(words, kkTens[tens])
+		}
+		if ones := g % 10; ones > 0 {
+			words = append(words, kkOnes[ones])
+		}
+		if i > 0 {
+			words = append(words, kkScales[i-1])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+var (
+	enOnes   = []string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = []string{"thousand", "million", "billion", "trillion"}
+)
+
+func enNumber(n int64) string {
+	if n == 0 {
+		return "zero"
+	}
+
+	groups := triples(n)
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+
+		if g >= 100 {
+			words = append(words, enOnes[g/100], "hundred")
+		}
+		switch rest := g % 100; {
+		case rest >= 20 && rest%10 != 0:
+			words = append(words, enTens[rest/10]+"-"+enOnes[rest%10])
+		case rest >= 20:
+			words = append(words, enTens[rest/10])
+		case rest > 0:
+			words = append(words, enOnes[rest])
+		}
+		if i > 0 {
+			words = append(words, enScales[i-1])
+		}
+	}
+	return strings.Join(words, " ")
+}
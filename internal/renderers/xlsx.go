@@ -0,0 +1,738 @@
+package renderers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// XlsxRenderer renders XLSX templates in-process, without the Python service.
+// Every sheet of the workbook is processed:
+//
+//   - text cells holding template tags are rendered with the request data.
+//     A cell that is a single {{ var }} gets the type of the value: numbers
+//     and booleans are written as such, and strings holding a number or a
+//     date become one when the cell has a number or date format;
+//   - a row with {{ table.field }} placeholders, where table is a list in the
+//     data, is written once per item with table bound to the item, keeping
+//     the styles, number formats and merged cells of the template row. An
+//     empty list leaves the row blank;
+//   - rows below a repeated row move down, and formulas, merged cells,
+//     conditional formats, defined names and drawings follow them. A range
+//     ending on a repeated row grows with it, so a totals row with
+//     =SUM(E5:E5) under table row 5 sums every item.
+//
+// Formulas are recalculated by Excel when the workbook is opened.
+type XlsxRenderer struct {
+	cache officeCache[*xlsxTemplate]
+}
+
+func NewXlsxRenderer(templateDir string) *XlsxRenderer {
+	return &XlsxRenderer{cache: officeCache[*xlsxTemplate]{templateDir: templateDir, ext: "xlsx", compile: compileXlsx}}
+}
+
+// NewCachedXlsxRenderer reads templates from registry.
+func NewCachedXlsxRenderer(registry *Registry) *XlsxRenderer {
+	r := NewXlsxRenderer(registry.Dir())
+	r.cache.registry = registry
+	return r
+}
+
+// CacheStats returns the lookups of compiled templates: misses compiled a
+// template that was new or had changed.
+func (r *XlsxRenderer) CacheStats() *CacheStats {
+	return &r.cache.stats
+}
+
+type xlsxTemplate struct {
+	sheets   []*xlsxSheet
+	workbook string
+	// static holds the parts that are rewritten the same way for every
+	// render: the calculation chain is dropped, as it no longer matches the
+	// cells once rows move.
+	static map[string][]byte
+}
+
+type xlsxSheet struct {
+	name, part string
+	// before and after are the sheet XML around the rows of <sheetData>
+	before, after string
+	rows          []*xlsxRow
+
+	drawing, drawingXML string // drawing anchored to the sheet, if any
+}
+
+type xlsxRow struct {
+	num   int
+	attrs []xmlAttr // without r
+	cells []*xlsxCell
+	// tables are the names the row's {{ table.field }} placeholders refer to
+	tables []string
+}
+
+type xlsxCell struct {
+	col   string
+	attrs []xmlAttr // without r and t
+	kind  string    // the t attribute
+	inner string
+	style numberFormat
+
+	formula  *xlsxFormula
+	template *pongo2.Template
+	path     []string // set when the cell is a single {{ var }}
+}
+
+type xlsxFormula struct {
+	attrs []xmlAttr
+	text  string
+}
+
+type xmlAttr struct {
+	name, value string // value as written, still escaped
+}
+
+var (
+	xlsxSheetData   = regexp.MustCompile(`(?s)<sheetData\s*/>|<sheetData>(.*?)</sheetData>`)
+	xlsxRowEl       = regexp.MustCompile(`(?s)<row\b([^>]*?)(?:/>|>(.*?)</row>)`)
+	xlsxCellEl      = regexp.MustCompile(`(?s)<c\b([^>]*?)(?:/>|>(.*?)</c>)`)
+	xlsxFormulaEl   = regexp.MustCompile(`(?s)<f\b([^>]*?)(?:/>|>(.*?)</f>)`)
+	xlsxValueEl     = regexp.MustCompile(`(?s)<v>(.*?)</v>`)
+	xlsxTextEl      = regexp.MustCompile(`(?s)<t\b[^>]*>(.*?)</t>`)
+	xlsxPhonetic    = regexp.MustCompile(`(?s)<rPh\b.*?</rPh>`)
+	xlsxStringItem  = regexp.MustCompile(`(?s)<si>(.*?)</si>|<si/>`)
+	xlsxMergeCells  = regexp.MustCompile(`(?s)<mergeCells\b[^>]*>(.*?)</mergeCells>`)
+	xlsxMergeCell   = regexp.MustCompile(`<mergeCell ref="([^"]*)"\s*/>`)
+	xlsxRangeAttr   = regexp.MustCompile(`\b(ref|sqref)="([^"]*)"`)
+	xlsxFormulaText = regexp.MustCompile(`(?s)(<formula\d?>)(.*?)(</formula\d?>)`)
+	xlsxDefinedName = regexp.MustCompile(`(?s)(<definedName\b[^>]*>)(.*?)(</definedName>)`)
+	xlsxAnchorRow   = regexp.MustCompile(`(?s)(<xdr:(?:from|to)>.*?<xdr:row>)(\d+)(</xdr:row>)`)
+	xmlAttrs        = regexp.MustCompile(`([\w:]+)="([^"]*)"`)
+	xlsxCalcPr      = xmlElement("calcPr")
+	xlsxCellRef     = regexp.MustCompile(`^([A-Za-z]{1,3})(\d+)$`)
+
+	// a cell that is nothing but {{ path }}, whose value keeps its type
+	xlsxPlaceholder = regexp.MustCompile(`^\s*\{\{-?\s*([A-Za-z_]\w*(?:\.\w+)*)\s*-?\}\}\s*$`)
+	xlsxTableRef    = regexp.MustCompile(`\{\{-?\s*([A-Za-z_]\w*)\.\w`)
+)
+
+// xmlElement matches the start tag of the elements called name.
+func xmlElement(name string) *regexp.Regexp {
+	return regexp.MustCompile(`<` + name + `\b[^>]*>`)
+}
+
+func parseAttrs(s string) []xmlAttr {
+	var attrs []xmlAttr
+	for _, m := range xmlAttrs.FindAllStringSubmatch(s, -1) {
+		attrs = append(attrs, xmlAttr{name: m[1], value: m[2]})
+	}
+	return attrs
+}
+
+func attrValue(attrs []xmlAttr, name string) string {
+	for _, a := range attrs {
+		if a.name == name {
+			return html.UnescapeString(a.value)
+		}
+	}
+	return ""
+}
+
+func withoutAttrs(attrs []xmlAttr, names ...string) []xmlAttr {
+	var out []xmlAttr
+	for _, a := range attrs {
+		drop := false
+		for _, name := range names {
+			drop = drop || a.name == name
+		}
+		if !drop {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func writeAttrs(b *strings.Builder, attrs []xmlAttr) {
+	for _, a := range attrs {
+		b.WriteString(" " + a.name + `="` + a.value + `"`)
+	}
+}
+
+// resolvePart resolves the target of a relationship of part to a part name.
+func resolvePart(part, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(path.Dir(part), target)
+}
+
+// relationships returns the targets of the relationships of part by id, and
+// the target of the first one whose type ends with typ.
+func relationships(parts map[string][]byte, part, typ string) (map[string]string, string) {
+	targets := map[string]string{}
+	first := ""
+	for _, el := range xmlElement("Relationship").FindAllString(string(parts[relsName(part)]), -1) {
+		attrs := parseAttrs(el)
+		target := resolvePart(part, attrValue(attrs, "Target"))
+		targets[attrValue(attrs, "Id")] = target
+		if first == "" && strings.HasSuffix(attrValue(attrs, "Type"), typ) {
+			first = target
+		}
+	}
+	return targets, first
+}
+
+func compileXlsx(source []byte) (*xlsxTemplate, error) {
+	parts, err := readZipParts(source, func(name string) bool {
+		return name == "[Content_Types].xml" ||
+			strings.HasPrefix(name, "xl/") && (strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".rels"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	const workbookPart = "xl/workbook.xml"
+	workbook, ok := parts[workbookPart]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", workbookPart)
+	}
+
+	sharedStrings := parseSharedStrings(string(parts["xl/sharedStrings.xml"]))
+	styles := parseNumberFormats(string(parts["xl/styles.xml"]))
+	targets, _ := relationships(parts, workbookPart, "")
+
+	tpl := &xlsxTemplate{workbook: string(workbook), static: map[string][]byte{}}
+	for _, el := range xmlElement("sheet").FindAllString(string(workbook), -1) {
+		attrs := parseAttrs(el)
+		name, part := attrValue(attrs, "name"), targets[attrValue(attrs, "r:id")]
+		data, ok := parts[part]
+		if !ok {
+			return nil, fmt.Errorf("sheet %q: %s is missing", name, part)
+		}
+
+		sheet, err := parseSheet(string(data), sharedStrings, styles)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", name, err)
+		}
+		sheet.name, sheet.part = name, part
+		if _, drawing := relationships(parts, part, "/drawing"); drawing != "" {
+			sheet.drawing, sheet.drawingXML = drawing, string(parts[drawing])
+		}
+		tpl.sheets = append(tpl.sheets, sheet)
+	}
+
+	const calcChain = "xl/calcChain.xml"
+	if _, ok := parts[calcChain]; ok {
+		tpl.static[calcChain] = nil
+		for _, p := range []struct{ name, element string }{
+			{relsName(workbookPart), "Relationship"},
+			{"[Content_Types].xml", "Override"},
+		} {
+			tpl.static[p.name] = []byte(xmlElement(p.element).ReplaceAllStringFunc(string(parts[p.name]), func(el string) string {
+				if strings.Contains(el, "calcChain") {
+					return ""
+				}
+				return el
+			}))
+		}
+	}
+	return tpl, nil
+}
+
+// parseSharedStrings returns the plain text of the shared strings.
+func parseSharedStrings(sst string) []string {
+	var strs []string
+	for _, m := range xlsxStringItem.FindAllStringSubmatch(sst, -1) {
+		strs = append(strs, richText(m[1]))
+	}
+	return strs
+}
+
+// richText returns the text of a string item, without its formatting.
+func richText(item string) string {
+	var b strings.Builder
+	for _, t := range xlsxTextEl.FindAllStringSubmatch(xlsxPhonetic.ReplaceAllString(item, ""), -1) {
+		b.WriteString(html.UnescapeString(t[1]))
+	}
+	return b.String()
+}
+
+func parseSheet(xml string, sharedStrings []string, styles []numberFormat) (*xlsxSheet, error) {
+	loc := xlsxSheetData.FindStringSubmatchIndex(xml)
+	if loc == nil {
+		return nil, fmt.Errorf("sheetData is missing")
+	}
+
+	sheet := &xlsxSheet{
+		before: xml[:loc[0]] + "<sheetData>",
+		after:  "</sheetData>" + xml[loc[1]:],
+	}
+	if loc[2] < 0 {
+		return sheet, nil
+	}
+
+	num := 0
+	for _, m := range xlsxRowEl.FindAllStringSubmatch(xml[loc[2]:loc[3]], -1) {
+		attrs := parseAttrs(m[1])
+		if r, err := strconv.Atoi(attrValue(attrs, "r")); err == nil {
+			num = r
+		} else {
+			num++
+		}
+
+		row := &xlsxRow{num: num, attrs: withoutAttrs(attrs, "r")}
+		for _, c := range xlsxCellEl.FindAllStringSubmatch(m[2], -1) {
+			cell, err := parseCell(c[1], c[2], sharedStrings, styles)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", num, err)
+			}
+			row.cells = append(row.cells, cell)
+
+			if cell.template != nil {
+				for _, t := range xlsxTableRef.FindAllStringSubmatch(cell.text(sharedStrings), -1) {
+					row.addTable(t[1])
+				}
+			}
+		}
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet, nil
+}
+
+func (row *xlsxRow) addTable(name string) {
+	for _, t := range row.tables {
+		if t == name {
+			return
+		}
+	}
+	row.tables = append(row.tables, name)
+}
+
+func parseCell(attrStr, inner string, sharedStrings []string, styles []numberFormat) (*xlsxCell, error) {
+	attrs := parseAttrs(attrStr)
+	cell := &xlsxCell{
+		attrs: withoutAttrs(attrs, "r", "t"),
+		kind:  attrValue(attrs, "t"),
+		inner: inner,
+	}
+	if m := xlsxCellRef.FindStringSubmatch(attrValue(attrs, "r")); m != nil {
+		cell.col = m[1]
+	}
+	if s, err := strconv.Atoi(attrValue(attrs, "s")); err == nil && s < len(styles) {
+		cell.style = styles[s]
+	}
+
+	if f := xlsxFormulaEl.FindStringSubmatch(inner); f != nil {
+		cell.formula = &xlsxFormula{attrs: parseAttrs(f[1]), text: html.UnescapeString(f[2])}
+		return cell, nil
+	}
+
+	text := cell.text(sharedStrings)
+	if !strings.Contains(text, "{{") && !strings.Contains(text, "{%") {
+		return cell, nil
+	}
+
+	var err error
+	if cell.template, err = compileString("{% autoescape off %}" + text + "{% endautoescape %}"); err != nil {
+		return nil, fmt.Errorf("cell %s: %w", cell.col, err)
+	}
+	if m := xlsxPlaceholder.FindStringSubmatch(text); m != nil {
+		cell.path = strings.Split(m[1], ".")
+	}
+	return cell, nil
+}
+
+// text returns the text of a string cell.
+func (c *xlsxCell) text(sharedStrings []string) string {
+	switch c.kind {
+	case "s":
+		m := xlsxValueEl.FindStringSubmatch(c.inner)
+		if m == nil {
+			return ""
+		}
+		if i, err := strconv.Atoi(m[1]); err == nil && i >= 0 && i < len(sharedStrings) {
+			return sharedStrings[i]
+		}
+	case "inlineStr":
+		return richText(c.inner)
+	}
+	return ""
+}
+
+// Render renders the XLSX template templateName with data.
+func (r *XlsxRenderer) Render(templateName string, data map[string]interface{}) ([]byte, error) {
+	out, _, err := r.RenderSource(templateName, data)
+	return out, err
+}
+
+// RenderSource renders the XLSX template templateName with data, and returns
+// the template file it was rendered from as well.
+func (r *XlsxRenderer) RenderSource(templateName string, data map[string]interface{}) ([]byte, []byte, error) {
+	tpl, source, err := r.cache.get(templateName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := pongo2.Context{}
+	for k, v := range data {
+		ctx[k] = v
+	}
+
+	shifts := map[string]*rowShift{}
+	for _, sheet := range tpl.sheets {
+		copies := map[int]int{}
+		for _, row := range sheet.rows {
+			if _, items, ok := row.items(data); ok {
+				copies[row.num] = max(len(items), 1)
+			}
+		}
+		shifts[sheet.name] = newRowShift(copies)
+	}
+
+	replaced := map[string][]byte{}
+	for name, part := range tpl.static {
+		replaced[name] = part
+	}
+	for _, sheet := range tpl.sheets {
+		out, err := sheet.render(ctx, shifts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sheet %q: %w", sheet.name, err)
+		}
+		replaced[sheet.part] = []byte(out)
+
+		if shift := shifts[sheet.name]; shift != nil && sheet.drawing != "" {
+			replaced[sheet.drawing] = []byte(xlsxAnchorRow.ReplaceAllStringFunc(sheet.drawingXML, func(s string) string {
+				m := xlsxAnchorRow.FindStringSubmatch(s)
+				row, _ := strconv.Atoi(m[2])
+				return m[1] + strconv.Itoa(shift.start(row+1)-1) + m[3]
+			}))
+		}
+	}
+	replaced["xl/workbook.xml"] = []byte(renderWorkbook(tpl.workbook, shifts))
+
+	out, err := rewriteZip(source, replaced, nil)
+	return out, source, err
+}
+
+// items returns the list the row is repeated for, if any.
+func (row *xlsxRow) items(data map[string]interface{}) (string, []interface{}, bool) {
+	for _, name := range row.tables {
+		if items, ok := data[name].([]interface{}); ok {
+			return name, items, true
+		}
+	}
+	return "", nil, false
+}
+
+func (s *xlsxSheet) render(ctx pongo2.Context, shifts map[string]*rowShift) (string, error) {
+	shift := shifts[s.name]
+	refs := refShifter{shifts: shifts, sheet: s.name}
+
+	var b strings.Builder
+	b.WriteString(refs.attrs(s.before))
+	for _, row := range s.rows {
+		table, items, ok := row.items(ctx)
+		if !ok {
+			if err := row.render(&b, ctx, refs, shift.start(row.num)); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		rowCtx := pongo2.Context{}
+		for k, v := range ctx {
+			rowCtx[k] = v
+		}
+		rowRefs := refs
+		rowRefs.row = row.num
+		for i := 0; i < max(len(items), 1); i++ {
+			rowCtx[table] = nil
+			if i < len(items) {
+				rowCtx[table] = items[i]
+			}
+			rowRefs.copy = i
+			if err := row.render(&b, rowCtx, rowRefs, shift.start(row.num)+i); err != nil {
+				return "", err
+			}
+		}
+	}
+	b.WriteString(s.renderAfter(refs, shift))
+	return b.String(), nil
+}
+
+func (row *xlsxRow) render(b *strings.Builder, ctx pongo2.Context, refs refShifter, num int) error {
+	b.WriteString(`<row r="` + strconv.Itoa(num) + `"`)
+	writeAttrs(b, row.attrs)
+	if len(row.cells) == 0 {
+		b.WriteString("/>")
+		return nil
+	}
+	b.WriteString(">")
+	for _, cell := range row.cells {
+		if err := cell.render(b, ctx, refs, num); err != nil {
+			return err
+		}
+	}
+	b.WriteString("</row>")
+	return nil
+}
+
+func (c *xlsxCell) render(b *strings.Builder, ctx pongo2.Context, refs refShifter, row int) error {
+	kind, inner := c.kind, c.inner
+	switch {
+	case c.formula != nil:
+		// the cached value is stale once references move; Excel recalculates
+		f := &strings.Builder{}
+		f.WriteString("<f")
+		for _, a := range c.formula.attrs {
+			if a.name == "ref" {
+				a.value = refs.ranges(a.value)
+			}
+			writeAttrs(f, []xmlAttr{a})
+		}
+		f.WriteString(">" + html.EscapeString(refs.formula(c.formula.text)) + "</f>")
+		kind, inner = "", f.String()
+	case c.path != nil:
+		kind, inner = typedValue(lookupPath(ctx, c.path), c.style)
+	case c.template != nil:
+		out, err := c.template.Execute(ctx)
+		if err != nil {
+			return fmt.Errorf("cell %s%d: %w", c.col, row, err)
+		}
+		kind, inner = inlineString(out)
+	}
+
+	b.WriteString("<c")
+	if c.col != "" {
+		b.WriteString(` r="` + c.col + strconv.Itoa(row) + `"`)
+	}
+	writeAttrs(b, c.attrs)
+	if kind != "" {
+		b.WriteString(` t="` + kind + `"`)
+	}
+	if inner == "" {
+		b.WriteString("/>")
+		return nil
+	}
+	b.WriteString(">" + inner + "</c>")
+	return nil
+}
+
+func lookupPath(ctx pongo2.Context, keys []string) interface{} {
+	var value interface{} = map[string]interface{}(ctx)
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func inlineString(s string) (string, string) {
+	if s == "" {
+		return "", ""
+	}
+	return "inlineStr", `<is><t xml:space="preserve">` + html.EscapeString(s) + `</t></is>`
+}
+
+func numberValue(n string) (string, string) {
+	return "", "<v>" + n + "</v>"
+}
+
+// excelEpoch is day zero of the serial dates of the 1900 date system.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// typedValue writes a value as the cell type that matches it.
+func typedValue(value interface{}, style numberFormat) (string, string) {
+	switch v := value.(type) {
+	case nil:
+		return "", ""
+	case bool:
+		if v {
+			return "b", "<v>1</v>"
+		}
+		return "b", "<v>0</v>"
+	case float64:
+		return numberValue(strconv.FormatFloat(v, 'f', -1, 64))
+	case int:
+		return numberValue(strconv.Itoa(v))
+	case json.Number:
+		return numberValue(v.String())
+	case string:
+		switch style {
+		case formatDate:
+			if t, err := parseTime(pongo2.AsValue(v)); err == nil {
+				wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+				return numberValue(strconv.FormatFloat(wall.Sub(excelEpoch).Hours()/24, 'f', -1, 64))
+			}
+		case formatNumber:
+			n := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(strings.TrimSpace(v))
+			if decimalPattern.MatchString(n) {
+				return numberValue(strings.TrimPrefix(n, "+"))
+			}
+		}
+		return inlineString(v)
+	default:
+		return inlineString(pongo2.AsValue(v).String())
+	}
+}
+
+// renderAfter shifts the ranges that follow the rows of a sheet: merged
+// cells, conditional formats, data validations, hyperlinks and so on.
+func (s *xlsxSheet) renderAfter(refs refShifter, shift *rowShift) string {
+	after := s.after
+	loc := xlsxMergeCells.FindStringIndex(after)
+	if loc == nil {
+		return refs.attrs(after)
+	}
+
+	var merges []string
+	for _, m := range xlsxMergeCell.FindAllStringSubmatch(after[loc[0]:loc[1]], -1) {
+		ref := m[1]
+		first, last, _ := strings.Cut(ref, ":")
+		if last == "" {
+			last = first
+		}
+		if fm, lm := xlsxCellRef.FindStringSubmatch(first), xlsxCellRef.FindStringSubmatch(last); fm != nil && lm != nil && fm[2] == lm[2] {
+			// a merge within a repeated row is repeated with it
+			row, _ := strconv.Atoi(fm[2])
+			if shift != nil && shift.copies[row] > 1 {
+				for i := 0; i < shift.copies[row]; i++ {
+					n := strconv.Itoa(shift.start(row) + i)
+					merges = append(merges, fm[1]+n+":"+lm[1]+n)
+				}
+				continue
+			}
+		}
+		merges = append(merges, refs.ranges(ref))
+	}
+
+	var b strings.Builder
+	b.WriteString(`<mergeCells count="` + strconv.Itoa(len(merges)) + `">`)
+	for _, ref := range merges {
+		b.WriteString(`<mergeCell ref="` + ref + `"/>`)
+	}
+	b.WriteString("</mergeCells>")
+	return refs.attrs(after[:loc[0]]) + b.String() + refs.attrs(after[loc[1]:])
+}
+
+// attrs shifts the ref and sqref attributes and the formulas of conditional
+// formats and data validations in a piece of sheet XML.
+func (rs refShifter) attrs(xml string) string {
+	xml = xlsxRangeAttr.ReplaceAllStringFunc(xml, func(s string) string {
+		m := xlsxRangeAttr.FindStringSubmatch(s)
+		return m[1] + `="` + rs.ranges(m[2]) + `"`
+	})
+	return xlsxFormulaText.ReplaceAllStringFunc(xml, func(s string) string {
+		m := xlsxFormulaText.FindStringSubmatch(s)
+		return m[1] + html.EscapeString(rs.formula(html.UnescapeString(m[2]))) + m[3]
+	})
+}
+
+// renderWorkbook shifts the defined names, such as print areas, and asks
+// Excel to recalculate every formula on open.
+func renderWorkbook(workbook string, shifts map[string]*rowShift) string {
+	refs := refShifter{shifts: shifts}
+	workbook = xlsxDefinedName.ReplaceAllStringFunc(workbook, func(s string) string {
+		m := xlsxDefinedName.FindStringSubmatch(s)
+		return m[1] + html.EscapeString(refs.formula(html.UnescapeString(m[2]))) + m[3]
+	})
+
+	if calcPr := xlsxCalcPr.FindStringIndex(workbook); calcPr != nil {
+		el := workbook[calcPr[0]:calcPr[1]]
+		if strings.Contains(el, "fullCalcOnLoad") {
+			return workbook
+		}
+		return workbook[:calcPr[0]+len("<calcPr")] + ` fullCalcOnLoad="1"` + workbook[calcPr[0]+len("<calcPr"):]
+	}
+
+	// calcPr follows sheets and the optional elements after it
+	pos := -1
+	for _, el := range []string{"</sheets>", "</functionGroups>", "<functionGroups/>", "</externalReferences>", "</definedNames>"} {
+		if i := strings.LastIndex(workbook, el); i >= 0 {
+			pos = max(pos, i+len(el))
+		}
+	}
+	if pos < 0 {
+		return workbook
+	}
+	return workbook[:pos] + `<calcPr fullCalcOnLoad="1"/>` + workbook[pos:]
+}
+
+type numberFormat int
+
+const (
+	formatGeneral numberFormat = iota
+	formatNumber
+	formatDate
+	formatText
+)
+
+// xlsxFormatLiterals are the parts of a format code that aren't date or
+// number placeholders: quoted text, colours and conditions, escaped
+// characters, and padding.
+var xlsxFormatLiterals = regexp.MustCompile(`"[^"]*"|\[[^\]]*\]|\\.|[_*].`)
+
+// parseNumberFormats returns the kind of number format of every cell style.
+func parseNumberFormats(styles string) []numberFormat {
+	custom := map[int]numberFormat{}
+	for _, el := range xmlElement("numFmt").FindAllString(styles, -1) {
+		attrs := parseAttrs(el)
+		id, err := strconv.Atoi(attrValue(attrs, "numFmtId"))
+		if err != nil {
+			continue
+		}
+		custom[id] = formatKind(attrValue(attrs, "formatCode"))
+	}
+
+	var formats []numberFormat
+	cellXfs := regexp.MustCompile(`(?s)<cellXfs\b[^>]*>(.*?)</cellXfs>`).FindStringSubmatch(styles)
+	if cellXfs == nil {
+		return nil
+	}
+	for _, el := range xmlElement("xf").FindAllString(cellXfs[1], -1) {
+		id, _ := strconv.Atoi(attrValue(parseAttrs(el), "numFmtId"))
+		kind, ok := custom[id]
+		if !ok {
+			kind = builtinFormat(id)
+		}
+		formats = append(formats, kind)
+	}
+	return formats
+}
+
+func builtinFormat(id int) numberFormat {
+	switch {
+	case id == 0:
+		return formatGeneral
+	case id == 49:
+		return formatText
+	case id >= 14 && id <= 22, id >= 27 && id <= 36, id >= 45 && id <= 47, id >= 50 && id <= 58:
+		return formatDate
+	default:
+		return formatNumber
+	}
+}
+
+func formatKind(code string) numberFormat {
+	if strings.EqualFold(code, "General") {
+		return formatGeneral
+	}
+	code = xlsxFormatLiterals.ReplaceAllString(code, "")
+	switch {
+	case code == "@":
+		return formatText
+	case strings.ContainsAny(code, "dmyhsDMYHS"):
+		return formatDate
+	default:
+		return formatNumber
+	}
+}
@@ -0,0 +1,153 @@
+package renderers
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rowShift maps the row numbers of a template sheet to the rendered sheet,
+// in which some rows of the template were repeated. A nil rowShift leaves
+// every row where it is.
+type rowShift struct {
+	rows   []int       // repeated template rows, ascending
+	copies map[int]int // how many times each of them was written
+}
+
+func newRowShift(copies map[int]int) *rowShift {
+	s := &rowShift{copies: map[int]int{}}
+	for row, n := range copies {
+		if n != 1 {
+			s.rows = append(s.rows, row)
+			s.copies[row] = n
+		}
+	}
+	if len(s.rows) == 0 {
+		return nil
+	}
+	sort.Ints(s.rows)
+	return s
+}
+
+// start returns where row r of the template, or its first copy, ends up.
+func (s *rowShift) start(r int) int {
+	if s == nil {
+		return r
+	}
+	n := r
+	for _, row := range s.rows {
+		if row >= r {
+			break
+		}
+		n += s.copies[row] - 1
+	}
+	return n
+}
+
+// end returns where the last copy of row r ends up, so that a range ending
+// on a repeated row covers all of its copies.
+func (s *rowShift) end(r int) int {
+	if s == nil {
+		return r
+	}
+	n := s.start(r)
+	if copies, ok := s.copies[r]; ok {
+		n += copies - 1
+	}
+	return n
+}
+
+// refShifter rewrites the cell references of formulas and ranges after rows
+// were repeated.
+type refShifter struct {
+	shifts map[string]*rowShift // by sheet name
+	sheet  string               // sheet of references without one, "" for none
+
+	// row is the template row being written, and copy which of its copies.
+	// References to row that aren't absolute follow the copy, so =D5*E5 in
+	// the second copy of row 5 becomes =D6*E6.
+	row, copy int
+}
+
+// cellRef matches A1 style references and ranges, optionally on another sheet.
+var cellRef = regexp.MustCompile(`(?:('(?:[^']|'')+'|[A-Za-z_][\w.]*)!)?(\$?)([A-Za-z]{1,3})(\$?)(\d+)(?::(\$?)([A-Za-z]{1,3})(\$?)(\d+))?`)
+
+// formula rewrites the references in an Excel formula, leaving string
+// literals alone.
+func (rs refShifter) formula(f string) string {
+	parts := strings.Split(f, `"`)
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = rs.refs(parts[i], true)
+	}
+	return strings.Join(parts, `"`)
+}
+
+// ranges rewrites a ref or sqref attribute: ranges separated by spaces.
+func (rs refShifter) ranges(s string) string {
+	return rs.refs(s, false)
+}
+
+func (rs refShifter) refs(s string, inFormula bool) string {
+	matches := cellRef.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		// skip names and functions that merely look like a reference, such
+		// as LOG10(
+		if inFormula && (m[0] > 0 && isNameChar(s[m[0]-1]) || m[1] < len(s) && (isNameChar(s[m[1]]) || s[m[1]] == '(')) {
+			continue
+		}
+
+		sheet, qualified := rs.sheet, m[2] >= 0
+		if qualified {
+			sheet = s[m[2]:m[3]]
+			if strings.HasPrefix(sheet, "'") {
+				sheet = strings.ReplaceAll(sheet[1:len(sheet)-1], "''", "'")
+			}
+		}
+		if sheet == "" {
+			continue
+		}
+		shift := rs.shifts[sheet]
+		local := rs.row > 0 && (!qualified || sheet == rs.sheet)
+
+		b.WriteString(s[last:m[0]])
+		if qualified {
+			b.WriteString(s[m[2]:m[3]] + "!")
+		}
+		b.WriteString(rs.cell(s, m[4:12], shift, local, false))
+		if m[12] >= 0 {
+			b.WriteString(":")
+			b.WriteString(rs.cell(s, m[12:20], shift, local, true))
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// cell writes one end of a reference, given the submatch indexes of its
+// $, column, $ and row.
+func (rs refShifter) cell(s string, m []int, shift *rowShift, local, end bool) string {
+	colAbs, col, rowAbs := s[m[0]:m[1]], s[m[2]:m[3]], s[m[4]:m[5]]
+	row, _ := strconv.Atoi(s[m[6]:m[7]])
+
+	switch {
+	case local && rowAbs == "" && row == rs.row:
+		row = shift.start(row) + rs.copy
+	case end:
+		row = shift.end(row)
+	default:
+		row = shift.start(row)
+	}
+	return colAbs + col + rowAbs + strconv.Itoa(row)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '.' || c == '$' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
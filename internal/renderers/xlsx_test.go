@@ -0,0 +1,185 @@
+package renderers_test
+
+import (
+	"RBKproject4/internal/renderers"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const xlsxNS = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+var xlsxFixture = map[string]string{
+	"[Content_Types].xml": `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/calcChain.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.calcChain+xml"/></Types>`,
+	"xl/workbook.xml": `<?xml version="1.0"?><workbook ` + xlsxNS + `><sheets>` +
+		`<sheet name="Statement" sheetId="1" r:id="rId1"/><sheet name="Summary" sheetId="2" r:id="rId2"/></sheets>` +
+		`<definedNames><definedName name="_xlnm.Print_Area" localSheetId="0">Statement!$A$1:$D$5</definedName></definedNames></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>` +
+		`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain" Target="calcChain.xml"/></Relationships>`,
+	"xl/calcChain.xml": `<calcChain ` + xlsxNS + `><c r="B4" i="1"/></calcChain>`,
+	"xl/sharedStrings.xml": `<sst ` + xlsxNS + `>` +
+		`<si><t>Client: {{ client }}</t></si>` +
+		// a placeholder split over two rich text runs
+		`<si><r><t>{{ transactions.</t></r><r><rPr><b/></rPr><t>date }}</t></r></si>` +
+		`<si><t>{{ transactions.amount }}</t></si>` +
+		`<si><t>{{ transactions.note|upper }}</t></si>` +
+		`<si><t>Total</t></si></sst>`,
+	"xl/styles.xml": `<styleSheet ` + xlsxNS + `><numFmts count="1"><numFmt numFmtId="164" formatCode="dd\.mm\.yyyy"/></numFmts>` +
+		`<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="4"/><xf numFmtId="164" applyNumberFormat="1"/></cellXfs></styleSheet>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0"?><worksheet ` + xlsxNS + `><dimension ref="A1:E5"/><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>0</v></c></row>` +
+		`<row r="3" spans="1:5"><c r="A3" s="2" t="s"><v>1</v></c><c r="B3" s="1" t="s"><v>2</v></c><c r="C3" t="s"><v>3</v></c><c r="E3"><f>B3*2</f><v>0</v></c></row>` +
+		`<row r="4"><c r="A4" t="s"><v>4</v></c><c r="B4" s="1"><f>SUM(B3:B3)</f><v>0</v></c><c r="C4" t="str"><f>IF(B4&gt;0,"B3",LOG10(B4))</f><v>B3</v></c></row>` +
+		`<row r="5"><c r="A5" t="inlineStr"><is><t>Signature</t></is></c></row>` +
+		`</sheetData><mergeCells count="2"><mergeCell ref="C3:D3"/><mergeCell ref="A5:D5"/></mergeCells>` +
+		`<conditionalFormatting sqref="B3:B4"><cfRule type="expression" priority="1"><formula>B3&gt;100</formula></cfRule></conditionalFormatting>` +
+		`</worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0"?><worksheet ` + xlsxNS + `><sheetData>` +
+		`<row r="1"><c r="A1"><f>Statement!B4</f></c><c r="B1" t="inlineStr"><is><t>{{ period }}</t></is></c></row>` +
+		`</sheetData></worksheet>`,
+}
+
+func newXlsxFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "STATEMENT.xlsx"), buildDocx(t, xlsxFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+var xlsxCellPattern = regexp.MustCompile(`<c r="([A-Z]+\d+)"[^>]*?(?:/>|>.*?</c>)`)
+
+// xlsxCells returns the cells of a rendered sheet by reference, unescaped.
+func xlsxCells(t *testing.T, sheet string) map[string]string {
+	t.Helper()
+	assertWellFormed(t, sheet)
+	cells := map[string]string{}
+	for _, m := range xlsxCellPattern.FindAllStringSubmatch(sheet, -1) {
+		cells[m[1]] = html.UnescapeString(m[0])
+	}
+	return cells
+}
+
+func TestXlsxRenderer_Render(t *testing.T) {
+	r := renderers.NewXlsxRenderer(newXlsxFixture(t))
+
+	out, err := r.Render("STATEMENT", map[string]interface{}{
+		"client": "Acme & Sons",
+		"period": "Q1",
+		"transactions": []interface{}{
+			map[string]interface{}{"date": "2025-01-31", "amount": 1500.5, "note": "rent"},
+			map[string]interface{}{"date": "2025-02-01", "amount": "2 000,25", "note": "fee"},
+			map[string]interface{}{"date": "soon", "amount": 3, "note": nil},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	parts := readParts(t, out)
+	cells := xlsxCells(t, parts["xl/worksheets/sheet1.xml"])
+
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"A1", `t="inlineStr"><is><t xml:space="preserve">Client: Acme & Sons</t>`},
+		// dates and numbers in cells with such a format are typed
+		{"A3", `s="2"><v>45688</v>`},
+		{"B3", `s="1"><v>1500.5</v>`},
+		{"B4", `s="1"><v>2000.25</v>`},
+		{"B5", `s="1"><v>3</v>`},
+		{"A5", `t="inlineStr"><is><t xml:space="preserve">soon</t>`},
+		{"C3", `<t xml:space="preserve">RENT</t>`},
+		{"C5", `<c r="C5"/>`},
+		// formulas follow their copy of the row
+		{"E4", `<f>B4*2</f>`},
+		{"E5", `<f>B5*2</f>`},
+		// the totals row moves down and its range grows with the table
+		{"A6", `<v>4</v>`},
+		{"B6", `<f>SUM(B3:B5)</f>`},
+		{"C6", `<f>IF(B6>0,"B3",LOG10(B6))</f>`},
+		{"A7", `<t>Signature</t>`},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(cells[tt.cell], tt.want) {
+			t.Errorf("%s = %s, want it to contain %s", tt.cell, cells[tt.cell], tt.want)
+		}
+	}
+	if strings.Contains(cells["B6"], "<v>") {
+		t.Errorf("B6 keeps a stale cached value: %s", cells["B6"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="4" spans="1:5">`,
+		`<mergeCells count="4"><mergeCell ref="C3:D3"/><mergeCell ref="C4:D4"/><mergeCell ref="C5:D5"/><mergeCell ref="A7:D7"/></mergeCells>`,
+		`<dimension ref="A1:E7"/>`,
+		`<conditionalFormatting sqref="B3:B6">`,
+		`<formula>B3&gt;100</formula>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet1 missing %s:\n%s", want, sheet)
+		}
+	}
+
+	summary := xlsxCells(t, parts["xl/worksheets/sheet2.xml"])
+	if !strings.Contains(summary["A1"], "<f>Statement!B6</f>") {
+		t.Errorf("reference from another sheet not shifted: %s", summary["A1"])
+	}
+	if !strings.Contains(summary["B1"], ">Q1<") {
+		t.Errorf("second sheet not rendered: %s", summary["B1"])
+	}
+
+	workbook := parts["xl/workbook.xml"]
+	assertWellFormed(t, workbook)
+	for _, want := range []string{"Statement!$A$1:$D$7", `<calcPr fullCalcOnLoad="1"/>`} {
+		if !strings.Contains(workbook, want) {
+			t.Errorf("workbook missing %s:\n%s", want, workbook)
+		}
+	}
+
+	if _, ok := parts["xl/calcChain.xml"]; ok {
+		t.Errorf("calcChain.xml not removed")
+	}
+	for _, name := range []string{"[Content_Types].xml", "xl/_rels/workbook.xml.rels"} {
+		if strings.Contains(parts[name], "calcChain") {
+			t.Errorf("%s still refers to calcChain.xml: %s", name, parts[name])
+		}
+	}
+}
+
+func TestXlsxRenderer_EmptyTable(t *testing.T) {
+	r := renderers.NewXlsxRenderer(newXlsxFixture(t))
+
+	out, err := r.Render("STATEMENT", map[string]interface{}{"transactions": []interface{}{}})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	cells := xlsxCells(t, readParts(t, out)["xl/worksheets/sheet1.xml"])
+
+	for ref, want := range map[string]string{
+		"A3": `<c r="A3" s="2"/>`,
+		"B3": `<c r="B3" s="1"/>`,
+		"B4": "<f>SUM(B3:B3)</f>",
+		"A5": "<t>Signature</t>",
+	} {
+		if !strings.Contains(cells[ref], want) {
+			t.Errorf("%s = %s, want it to contain %s", ref, cells[ref], want)
+		}
+	}
+}
+
+func TestXlsxRenderer_MissingTemplate(t *testing.T) {
+	r := renderers.NewXlsxRenderer(t.TempDir())
+	if _, err := r.Render("missing", nil); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,30 @@
+// Package reqctx carries per-request identity through context.Context so that
+// services can use it without depending on gin.
+package reqctx
+
+import "context"
+
+type ctxKey int
+
+const (
+	clientIDKey ctxKey = iota
+	requestIDKey
+)
+
+func WithClientID(ctx context.Context, clientID string) context.Context {
+	return context.WithValue(ctx, clientIDKey, clientID)
+}
+
+func ClientID(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey).(string)
+	return id
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"RBKproject4/internal/health"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/upstream"
+	"RBKproject4/pkg/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// newHealthChecker checks what generation depends on: both upstreams and
+// their breakers, the template directory and the templates loaded from it.
+func newHealthChecker(cfg *config.Config, logger *slog.Logger, client *http.Client, templates *renderers.Registry, python, gotenberg *upstream.Client) *health.Checker {
+	checker := health.NewChecker(cfg.ReadinessCacheTTL, cfg.ReadinessTimeout)
+	checker.Add("gotenberg", health.HTTPCheck(client, strings.TrimSuffix(cfg.PDFConverterURL, "/")+"/health"))
+	checker.Add("gotenbergCircuit", circuitCheck(gotenberg))
+	checker.Add("python", health.HTTPCheck(client, strings.TrimSuffix(cfg.PythonURL, "/")+"/health"))
+	checker.Add("pythonCircuit", circuitCheck(python))
+	checker.Add("templateDir", health.DirCheck(logger, cfg.TemplateDir))
+	checker.Add("templates", templatesCheck(templates))
+	return checker
+}
+
+// circuitCheck fails while the breaker of an upstream is open.
+func circuitCheck(client *upstream.Client) health.CheckFunc {
+	return func(context.Context) error {
+		if state := client.State(); state == upstream.StateOpen {
+			return fmt.Errorf("circuit %s", state)
+		}
+		return nil
+	}
+}
+
+// templatesCheck fails while a template file on disk doesn't parse.
+func templatesCheck(templates *renderers.Registry) health.CheckFunc {
+	return func(context.Context) error {
+		var broken []string
+		for _, status := range templates.Status() {
+			if status.LastError != "" {
+				broken = append(broken, status.Name)
+			}
+		}
+		if len(broken) > 0 {
+			return fmt.Errorf("templates failing to load: %s", strings.Join(broken, ", "))
+		}
+		return nil
+	}
+}
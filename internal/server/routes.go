@@ -11,13 +11,33 @@ func (s *Server) setupRoutes() {
 	s.Router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "ok"})
 	})
+	s.Router.GET("/livez", s.HealthHandler.Livez)
+	s.Router.GET("/readyz", s.HealthHandler.Readyz)
+	s.Router.GET("/metrics", gin.WrapH(s.Metrics.Handler()))
 
 	api := s.Router.Group("/api/v1")
 	docGeneration := api.Group(s.Cfg.ServiceContextURL)
-	docGeneration.Use(middleware.AuthMiddleware(s.Cfg.StaticToken))
+	docGeneration.Use(middleware.AuthMiddleware(s.Clients))
 
-	docGeneration.POST("/generate-docx", s.DocumentHandler.GenerateDocument)
-	docGeneration.POST("/generate-xlsx", s.DocumentHandler.GenerateXLSX)
-	docGeneration.POST("/generate-html", s.DocumentHandler.GenerateHTML)
+	docGeneration.POST("/documents/:code", s.DocumentHandler.GenerateDocument)
+	// the /generate routes predate /documents/:code and take the code and
+	// format from the body
+	docGeneration.POST("/generate", s.DocumentHandler.Generate)
+	docGeneration.POST("/generate-docx", s.DocumentHandler.Generate)
+	docGeneration.POST("/generate-xlsx", s.DocumentHandler.Generate)
+	docGeneration.POST("/generate-html", s.DocumentHandler.Generate)
+	docGeneration.POST("/generate-batch", s.DocumentHandler.GenerateBatch)
+	docGeneration.POST("/compose", s.DocumentHandler.Compose)
+	docGeneration.POST("/validate", s.DocumentHandler.Validate)
 	docGeneration.GET("/templates", s.DocumentHandler.ListTemplates)
+	docGeneration.GET("/templates/:code", s.DocumentHandler.GetTemplate)
+
+	docGeneration.POST("/jobs", s.JobHandler.CreateJob)
+	docGeneration.GET("/jobs/:id", s.JobHandler.GetJob)
+	docGeneration.GET("/jobs/:id/result", s.JobHandler.GetJobResult)
+
+	admin := docGeneration.Group("/admin", middleware.RequireAdmin())
+	admin.GET("/templates", s.AdminHandler.TemplateStatus)
+	admin.GET("/audit", s.AdminHandler.AuditRecords)
+	admin.GET("/audit/verify", s.AdminHandler.VerifyAudit)
 }
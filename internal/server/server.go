@@ -1,16 +1,28 @@
 package server
 
 import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/clients"
 	"RBKproject4/internal/handlers"
+	"RBKproject4/internal/health"
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/metrics"
+	"RBKproject4/internal/middleware"
 	"RBKproject4/internal/renderers"
 	"RBKproject4/internal/services"
+	"RBKproject4/internal/upstream"
+	"RBKproject4/internal/webhooks"
 	"RBKproject4/pkg/config"
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Server struct {
@@ -18,12 +30,32 @@ type Server struct {
 	HTTPServer      *http.Server
 	HTTPClient      *http.Client
 	DocumentHandler *handlers.DocumentHandler
+	JobHandler      *handlers.JobHandler
+	JobService      *services.JobService
+	AdminHandler    *handlers.AdminHandler
+	HealthHandler   *handlers.HealthHandler
+	Health          *health.Checker
+	Templates       *renderers.Registry
 	Cfg             *config.Config
 	Logger          *slog.Logger
+	Metrics         *metrics.Metrics
+	Audit           *audit.Log
+	Clients         *clients.Registry
+	auditSink       io.Closer
 }
 
-func NewServer(cfg *config.Config, logger *slog.Logger) *Server {
-	router := gin.Default()
+func NewServer(cfg *config.Config, logger *slog.Logger) (*Server, error) {
+	m := metrics.New()
+	router := gin.New()
+	// the trace span comes first, so that it covers the other middleware, and
+	// the request ID before anything that logs
+	router.Use(
+		otelgin.Middleware(cfg.TracingServiceName),
+		middleware.RequestID(),
+		middleware.AccessLog(logger),
+		middleware.Metrics(m),
+		gin.Recovery(),
+	)
 
 	httpServer := &http.Server{
 		Addr:         cfg.ListenAddr,
@@ -33,14 +65,74 @@ func NewServer(cfg *config.Config, logger *slog.Logger) *Server {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	httpClient := &http.Client{
-		Timeout: 15 * time.Second,
+	// each upstream call has its own timeout, see newUpstreams
+	httpClient := &http.Client{}
+	python, gotenberg := newUpstreams(cfg, m)
+	m.WatchBreaker(python)
+	m.WatchBreaker(gotenberg)
+
+	templates, err := renderers.NewRegistry(cfg.TemplateDir, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := templates.Watch(); err != nil {
+		return nil, err
+	}
+
+	templateRenderer := renderers.NewCachedPongo2Renderer(templates)
+	docxRenderer := renderers.NewCachedDocxRenderer(templates)
+	xlsxRenderer := renderers.NewCachedXlsxRenderer(templates)
+	m.WatchCache("registry", templates.CacheStats())
+	m.WatchCache("docx", docxRenderer.CacheStats())
+	m.WatchCache("xlsx", xlsxRenderer.CacheStats())
+
+	registry, err := newClientRegistry(cfg)
+	if err != nil {
+		templates.Close()
+		return nil, err
+	}
+
+	auditLog, auditSink, err := newAuditLog(cfg, logger)
+	if err != nil {
+		templates.Close()
+		return nil, err
 	}
 
-	templateRenderer := renderers.NewPongo2Renderer(cfg.TemplateDir)
-	newDocService := services.NewDocumentService(logger, templateRenderer, cfg.PythonURL, cfg.TemplateDir, cfg.PDFConverterURL, httpClient)
+	newDocService := services.NewDocumentService(logger, templateRenderer, cfg.PythonURL, cfg.TemplateDir, cfg.PDFConverterURL, httpClient,
+		services.WithBatchLimits(cfg.BatchParallelism, cfg.BatchMaxItems),
+		services.WithBatchTimeout(cfg.BatchTimeout),
+		services.WithComposeLimits(cfg.ComposeMaxParts, cfg.ComposeTimeout),
+		services.WithStrictVariables(cfg.StrictVariables),
+		services.WithTemplateRegistry(templates),
+		services.WithUpstreams(python, gotenberg),
+		services.WithMetrics(m),
+		services.WithAuditLog(auditLog),
+		services.WithClientRegistry(registry),
+		services.WithNativeRenderer("docx", docxRenderer),
+		services.WithNativeRenderer("xlsx", xlsxRenderer))
 	newDocHandler := handlers.NewDocumentHandler(newDocService)
 
+	jobStore, err := newJobStore(cfg)
+	if err != nil {
+		templates.Close()
+		if auditSink != nil {
+			auditSink.Close()
+		}
+		return nil, err
+	}
+	callbackPolicy := webhooks.HostPolicy{Allowed: cfg.WebhookAllowedHosts, Private: cfg.WebhookAllowPrivate}
+	notifier := webhooks.NewNotifier(logger, callbackPolicy.Client(cfg.WebhookTimeout), cfg.WebhookSecrets, cfg.WebhookMaxAttempts, cfg.WebhookBackoff)
+	resultURL := func(id string) string {
+		return strings.TrimSuffix(cfg.PublicBaseURL, "/") + "/api/v1" + cfg.ServiceContextURL + "/jobs/" + id + "/result"
+	}
+	newJobService := services.NewJobService(logger, newDocService, jobStore, cfg.JobWorkers, cfg.JobQueueSize,
+		services.WithNotifier(notifier, resultURL),
+		services.WithCallbackPolicy(callbackPolicy),
+		services.WithRetention(cfg.JobRetention, cfg.JobCleanupInterval))
+	newJobHandler := handlers.NewJobHandler(newJobService)
+
+	checker := newHealthChecker(cfg, logger, httpClient, templates, python, gotenberg)
+
 	server := &Server{
 		Router:          router,
 		Cfg:             cfg,
@@ -48,10 +140,78 @@ func NewServer(cfg *config.Config, logger *slog.Logger) *Server {
 		HTTPServer:      httpServer,
 		HTTPClient:      httpClient,
 		DocumentHandler: newDocHandler,
+		JobHandler:      newJobHandler,
+		JobService:      newJobService,
+		AdminHandler:    handlers.NewAdminHandler(templates, auditLog),
+		HealthHandler:   handlers.NewHealthHandler(checker),
+		Health:          checker,
+		Metrics:         m,
+		Templates:       templates,
+		Audit:           auditLog,
+		Clients:         registry,
+		auditSink:       auditSink,
 	}
 
 	server.setupRoutes()
-	return server
+	return server, nil
+}
+
+// newUpstreams makes the clients for the Python service and Gotenberg, each
+// with its own timeout and breaker, and measured in m.
+func newUpstreams(cfg *config.Config, m *metrics.Metrics) (python, gotenberg *upstream.Client) {
+	opts := upstream.Options{
+		MaxAttempts:      cfg.UpstreamMaxAttempts,
+		Backoff:          cfg.UpstreamBackoff,
+		FailureThreshold: cfg.BreakerFailureThreshold,
+		OpenDuration:     cfg.BreakerOpenDuration,
+	}
+	pythonOpts, gotenbergOpts := opts, opts
+	pythonOpts.Timeout = cfg.PythonTimeout
+	gotenbergOpts.Timeout = cfg.GotenbergTimeout
+	return upstream.New("python", &http.Client{Transport: m.Transport("python", nil)}, pythonOpts),
+		upstream.New("gotenberg", &http.Client{Transport: m.Transport("gotenberg", nil)}, gotenbergOpts)
+}
+
+// newClientRegistry loads the clients file, or makes a single client of the
+// static token when there is none.
+func newClientRegistry(cfg *config.Config) (*clients.Registry, error) {
+	if cfg.ClientsFile == "" {
+		return clients.Static(cfg.StaticToken), nil
+	}
+	return clients.Load(cfg.ClientsFile)
+}
+
+// newAuditLog opens the audit log, continuing the chain already in it. The
+// returned closer is nil when there is nothing to close.
+func newAuditLog(cfg *config.Config, logger *slog.Logger) (*audit.Log, io.Closer, error) {
+	switch cfg.AuditSink {
+	case "none":
+		return nil, nil, nil
+	case "file":
+		sink, err := audit.NewFileSink(logger, cfg.AuditFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		log, err := audit.NewLog(context.Background(), sink)
+		if err != nil {
+			sink.Close()
+			return nil, nil, err
+		}
+		return log, sink, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown audit sink %q", cfg.AuditSink)
+	}
+}
+
+func newJobStore(cfg *config.Config) (jobs.Store, error) {
+	switch cfg.JobStore {
+	case "memory":
+		return jobs.NewMemoryStore(), nil
+	case "file":
+		return jobs.NewFileStore(cfg.JobStoreDir)
+	default:
+		return nil, fmt.Errorf("unknown job store %q", cfg.JobStore)
+	}
 }
 
 func (s *Server) Run() error {
@@ -59,16 +219,36 @@ func (s *Server) Run() error {
 	return s.HTTPServer.ListenAndServe()
 }
 
+// Shutdown first fails readiness and keeps serving for the drain delay, so
+// that load balancers stop sending traffic before connections are closed.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.Logger.Info("Shutting down server...")
+	s.Health.Drain()
 	done := make(chan error, 1)
 
 	go func() {
+		select {
+		case <-time.After(s.Cfg.ShutdownDrainDelay):
+		case <-ctx.Done():
+		}
 		if err := s.HTTPServer.Shutdown(ctx); err != nil {
 			s.Logger.Error("Failed to shutdown http server", "error", err)
 			done <- err
 			return
 		}
+		if err := s.JobService.Close(ctx); err != nil {
+			s.Logger.Error("Failed to stop job workers", "error", err)
+			done <- err
+			return
+		}
+		if err := s.Templates.Close(); err != nil {
+			s.Logger.Error("Failed to stop template watcher", "error", err)
+		}
+		if s.auditSink != nil {
+			if err := s.auditSink.Close(); err != nil {
+				s.Logger.Error("Failed to close audit log", "error", err)
+			}
+		}
 		s.Logger.Info("Graceful shutdown completed")
 		done <- nil
 	}()
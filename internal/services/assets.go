@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	assetsDirName    = "assets"
+	sharedAssetsName = "shared"
+)
+
+var (
+	htmlRefPattern = regexp.MustCompile(`(?i)\b(?:src|href)\s*=\s*["']([^"']+)["']`)
+	cssURLPattern  = regexp.MustCompile(`(?i)url\(\s*["']?([^"')]+?)["']?\s*\)`)
+)
+
+// assetCollector finds the files a rendered template refers to in
+// <TemplateDir>/assets/<CODE> and, failing that, <TemplateDir>/assets/shared.
+// Gotenberg puts every uploaded file in one flat folder, so references are
+// rewritten to bare file names as they are resolved.
+type assetCollector struct {
+	dirs  []string
+	files []formFile
+	// seen maps the path of every queued file to its upload name
+	seen map[string]string
+	// taken holds the upload names in use, the pages' own included
+	taken map[string]bool
+	// read holds the contents of every queued file as read, by path
+	read map[string][]byte
+}
+
+func (s *DocumentService) newAssetCollector(code string) *assetCollector {
+	root := filepath.Join(s.templateDir, assetsDirName)
+	return &assetCollector{
+		dirs:  []string{filepath.Join(root, code), filepath.Join(root, sharedAssetsName)},
+		seen:  map[string]string{},
+		read:  map[string][]byte{},
+		taken: map[string]bool{"index.html": true, "header.html": true, "footer.html": true},
+	}
+}
+
+// htmlWithAssets returns the files to send to Chromium: the rendered page as
+// index.html followed by every asset it (or a stylesheet it links) refers to.
+func (s *DocumentService) htmlWithAssets(ctx context.Context, code, html string) ([]formFile, error) {
+	c := s.newAssetCollector(code)
+
+	page, err := c.rewrite(html, htmlRefPattern, cssURLPattern)
+	if err != nil {
+		return nil, err
+	}
+	for filePath, data := range c.read {
+		name, err := filepath.Rel(s.templateDir, filePath)
+		if err != nil {
+			name = filePath
+		}
+		addTemplateSource(ctx, filepath.ToSlash(name), data)
+	}
+
+	return append([]formFile{{name: "index.html", data: []byte(page)}}, c.files...), nil
+}
+
+func (c *assetCollector) rewrite(content string, patterns ...*regexp.Regexp) (string, error) {
+	var firstErr error
+	for _, pattern := range patterns {
+		content = replaceSubmatch(pattern, content, func(ref string) string {
+			name, err := c.attach(ref)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if name == "" {
+				return ref
+			}
+			return name
+		})
+	}
+	return content, firstErr
+}
+
+// attach resolves ref against the asset dirs and queues the file once. It
+// returns the name the file is uploaded under, or "" when ref is not a local
+// asset.
+func (c *assetCollector) attach(ref string) (string, error) {
+	filePath := c.resolve(ref)
+	if filePath == "" {
+		return "", nil
+	}
+
+	if name, ok := c.seen[filePath]; ok {
+		return name, nil
+	}
+	name := c.uploadName(filePath)
+	c.seen[filePath] = name
+	c.taken[name] = true
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error reading asset %s: %w", name, err)
+	}
+	c.read[filePath] = data
+
+	// stylesheets pull in fonts and background images of their own
+	if strings.EqualFold(filepath.Ext(name), ".css") {
+		css, err := c.rewrite(string(data), cssURLPattern)
+		if err != nil {
+			return "", err
+		}
+		data = []byte(css)
+	}
+
+	c.files = append(c.files, formFile{name: name, data: data})
+	return name, nil
+}
+
+// uploadName is the file's own name, or, when another file already goes by
+// it, the name prefixed with a hash of the file's path.
+func (c *assetCollector) uploadName(filePath string) string {
+	name := filepath.Base(filePath)
+	if !c.taken[name] {
+		return name
+	}
+	sum := sha256.Sum256([]byte(filePath))
+	return hex.EncodeToString(sum[:4]) + "_" + name
+}
+
+func (c *assetCollector) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if ref == "" || strings.HasPrefix(ref, "//") || strings.Contains(ref, ":") {
+		return ""
+	}
+
+	// cleaning against a virtual root drops any leading "..", so a reference
+	// can never leave the asset dirs
+	clean := strings.TrimPrefix(path.Clean("/"+ref), "/")
+	if clean == "" {
+		return ""
+	}
+
+	for _, dir := range c.dirs {
+		candidate := filepath.Join(dir, filepath.FromSlash(clean))
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// replaceSubmatch replaces the first capture group of every match of pattern.
+func replaceSubmatch(pattern *regexp.Regexp, content string, replace func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		b.WriteString(content[last:m[2]])
+		b.WriteString(replace(content[m[2]:m[3]]))
+		last = m[3]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
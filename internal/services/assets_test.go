@@ -0,0 +1,145 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeneratePDF_AttachesReferencedAssets(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "CARD.html"), `<html><head>
+<link rel="stylesheet" href="fonts.css">
+<link rel="stylesheet" href="https://cdn.example.com/remote.css">
+</head><body>
+<img src="rbk_logo.jpg">
+<img src="img/stamp.png?v=2">
+<img src="../../secret.txt">
+<div style="background: url('missing.png')"></div>
+</body></html>`)
+	writeFile(t, filepath.Join(tmpDir, "secret.txt"), "do not send")
+	writeFile(t, filepath.Join(tmpDir, "assets", "CARD", "rbk_logo.jpg"), "code-logo")
+	writeFile(t, filepath.Join(tmpDir, "assets", "CARD", "img", "stamp.png"), "stamp")
+	writeFile(t, filepath.Join(tmpDir, "assets", "shared", "rbk_logo.jpg"), "shared-logo")
+	writeFile(t, filepath.Join(tmpDir, "assets", "shared", "fonts.css"),
+		`@font-face { font-family: "PT Sans"; src: url("fonts/PTSans-Regular.ttf") format("truetype"); }`)
+	writeFile(t, filepath.Join(tmpDir, "assets", "shared", "fonts", "PTSans-Regular.ttf"), "font")
+	writeFile(t, filepath.Join(tmpDir, "assets", "shared", "unused.png"), "unused")
+
+	received := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, fh := range r.MultipartForm.File["files"] {
+			f, _ := fh.Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			received[fh.Filename] = string(data)
+		}
+		w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, srv.URL, srv.Client())
+
+	if _, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: "CARD", Format: "pdf"}); err != nil {
+		t.Fatalf("GeneratePDF() error: %v", err)
+	}
+
+	var names []string
+	for name := range received {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"PTSans-Regular.ttf", "fonts.css", "index.html", "rbk_logo.jpg", "stamp.png"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("uploaded files = %v, want %v", names, want)
+	}
+
+	if received["rbk_logo.jpg"] != "code-logo" {
+		t.Errorf("per-template asset should win over shared, got %q", received["rbk_logo.jpg"])
+	}
+
+	index := received["index.html"]
+	for _, ref := range []string{`src="stamp.png"`, `href="https://cdn.example.com/remote.css"`, `src="../../secret.txt"`, `url('missing.png')`} {
+		if !strings.Contains(index, ref) {
+			t.Errorf("index.html should contain %s:\n%s", ref, index)
+		}
+	}
+	if !strings.Contains(received["fonts.css"], `url("PTSans-Regular.ttf")`) {
+		t.Errorf("font reference in fonts.css was not flattened: %s", received["fonts.css"])
+	}
+}
+
+func TestGeneratePDF_AssetsWithTheSameName(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "CARD.html"), `<img src="front/logo.png"><img src="back/logo.png"><img src="front/logo.png"><a href="footer.html"></a>`)
+	writeFile(t, filepath.Join(tmpDir, "assets", "CARD", "front", "logo.png"), "front")
+	writeFile(t, filepath.Join(tmpDir, "assets", "CARD", "back", "logo.png"), "back")
+	writeFile(t, filepath.Join(tmpDir, "assets", "CARD", "footer.html"), "not the footer")
+
+	received := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, fh := range r.MultipartForm.File["files"] {
+			f, _ := fh.Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			received[fh.Filename] = string(data)
+		}
+		w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, srv.URL, srv.Client())
+	if _, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: "CARD", Format: "pdf"}); err != nil {
+		t.Fatalf("GeneratePDF() error: %v", err)
+	}
+
+	if len(received) != 4 {
+		t.Fatalf("uploaded files = %v, want index.html and three assets", received)
+	}
+	if _, ok := received["footer.html"]; ok {
+		t.Error("an asset was uploaded as footer.html")
+	}
+
+	// every reference must lead to the file it named
+	refs := regexp.MustCompile(`(?:src|href)="([^"]+)"`).FindAllStringSubmatch(received["index.html"], -1)
+	want := []string{"front", "back", "front", "not the footer"}
+	if len(refs) != len(want) {
+		t.Fatalf("index.html = %s", received["index.html"])
+	}
+	for i, ref := range refs {
+		if received[ref[1]] != want[i] {
+			t.Errorf("reference %d = %q, uploaded as %q, want %q", i, ref[1], received[ref[1]], want[i])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/reqctx"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WithAuditLog records every generated document in log.
+func WithAuditLog(log *audit.Log) Option {
+	return func(s *DocumentService) {
+		s.audit = log
+	}
+}
+
+type templateSourcesKey struct{}
+
+// templateSources collects the files a document is rendered from, as the
+// renderers read them: the template, its header and footer and the assets
+// sent along. Hashing them afterwards instead of reading the files again
+// keeps a reload in between out of the audit record.
+type templateSources struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// withTemplateSources returns a context whose renders are collected in the
+// returned templateSources.
+func withTemplateSources(ctx context.Context) (context.Context, *templateSources) {
+	sources := &templateSources{files: map[string][]byte{}}
+	return context.WithValue(ctx, templateSourcesKey{}, sources), sources
+}
+
+// addTemplateSource records that the document of ctx was rendered from
+// source, the contents of the file name.
+func addTemplateSource(ctx context.Context, name string, source []byte) {
+	sources, _ := ctx.Value(templateSourcesKey{}).(*templateSources)
+	if sources == nil {
+		return
+	}
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+	sources.files[name] = source
+}
+
+// version is the SHA-256 of the only file, or, when there are several, of a
+// listing of their hashes and names as sha256sum prints it. It is empty when
+// nothing was collected.
+func (t *templateSources) version() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch len(t.files) {
+	case 0:
+		return ""
+	case 1:
+		for _, source := range t.files {
+			return audit.SHA256(source)
+		}
+	}
+	names := make([]string, 0, len(t.files))
+	for name := range t.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var listing strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&listing, "%s  %s\n", audit.SHA256(t.files[name]), name)
+	}
+	return audit.SHA256([]byte(listing.String()))
+}
+
+// recordAudit adds a document generated from data to the audit log, with the
+// version of the template it was rendered from when source is set. A
+// document that can't be recorded must not be handed out.
+func (s *DocumentService) recordAudit(ctx context.Context, code, source, format string, data any, output []byte) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	rec := &models.AuditRecord{
+		ClientID:     reqctx.ClientID(ctx),
+		RequestID:    reqctx.RequestID(ctx),
+		Code:         code,
+		Format:       format,
+		OutputSHA256: audit.SHA256(output),
+		Size:         len(output),
+	}
+	if sources, _ := ctx.Value(templateSourcesKey{}).(*templateSources); sources != nil {
+		rec.TemplateVersion = sources.version()
+	}
+	if rec.TemplateVersion == "" && source != "" {
+		// a generator that renders on its own only has the file as it is now
+		template, err := s.templateFile(code, source)
+		if err != nil {
+			return fmt.Errorf("error reading template for audit: %w", err)
+		}
+		rec.TemplateVersion = audit.SHA256(template)
+	}
+	// maps marshal with sorted keys, so the same data always hashes the same
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error hashing data for audit: %w", err)
+	}
+	rec.DataSHA256 = audit.SHA256(raw)
+
+	if err := s.audit.Record(ctx, rec); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record generated document", "code", code, "error", err)
+		return err
+	}
+	return nil
+}
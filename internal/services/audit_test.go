@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/reqctx"
+	"RBKproject4/internal/services"
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate_Audited(t *testing.T) {
+	tmpDir := t.TempDir()
+	template := []byte("<p>{{ name }}</p>")
+	if err := os.WriteFile(filepath.Join(tmpDir, "PDP.html"), template, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&upstreams{})
+	defer srv.Close()
+
+	sink, err := audit.NewFileSink(nil, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	auditLog, err := audit.NewLog(context.Background(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, srv.Client(),
+		services.WithAuditLog(auditLog))
+
+	ctx := reqctx.WithRequestID(reqctx.WithClientID(context.Background(), "mobile"), "req-1")
+	data := map[string]any{"name": "Aigerim"}
+	doc, err := svc.Generate(ctx, &models.RequestBody{Code: "PDP", Format: "pdf", Data: data})
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if _, err := svc.Generate(ctx, &models.RequestBody{Code: "MISSING", Format: "pdf"}); err == nil {
+		t.Fatal("Generate() of a missing template succeeded")
+	}
+
+	page, err := auditLog.Query(context.Background(), models.AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 1 {
+		t.Fatalf("got %d records, want only the generated document", len(page.Records))
+	}
+	rec := page.Records[0]
+	want := models.AuditRecord{
+		ClientID:        "mobile",
+		RequestID:       "req-1",
+		Code:            "PDP",
+		TemplateVersion: audit.SHA256(template),
+		Format:          "pdf",
+		DataSHA256:      audit.SHA256([]byte(`{"name":"Aigerim"}`)),
+		OutputSHA256:    audit.SHA256(doc.Data),
+		Size:            len(doc.Data),
+	}
+	if rec.ClientID != want.ClientID || rec.RequestID != want.RequestID || rec.Code != want.Code ||
+		rec.TemplateVersion != want.TemplateVersion || rec.Format != want.Format || rec.DataSHA256 != want.DataSHA256 ||
+		rec.OutputSHA256 != want.OutputSHA256 || rec.Size != want.Size {
+		t.Errorf("record = %+v, want %+v", rec, want)
+	}
+	if rec.Time.IsZero() || rec.Hash != audit.Hash(&rec) {
+		t.Errorf("record not timestamped and hashed: %+v", rec)
+	}
+}
+
+func TestGenerate_AuditsTheSourcesRendered(t *testing.T) {
+	tmpDir := t.TempDir()
+	page := []byte("<p>{{ name }}</p>")
+	footer := []byte("<p>page footer</p>")
+	writeFile(t, filepath.Join(tmpDir, "PDP.html"), string(page))
+	writeFile(t, filepath.Join(tmpDir, "PDP.footer.html"), string(footer))
+
+	srv := httptest.NewServer(&upstreams{})
+	defer srv.Close()
+
+	sink, err := audit.NewFileSink(nil, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	auditLog, err := audit.NewLog(context.Background(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry, err := renderers.NewRegistry(tmpDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Close()
+	svc := services.NewDocumentService(logger, renderers.NewCachedPongo2Renderer(registry), srv.URL, tmpDir, srv.URL, srv.Client(),
+		services.WithTemplateRegistry(registry), services.WithAuditLog(auditLog))
+
+	// an edit the registry hasn't reloaded yet is not what gets rendered
+	writeFile(t, filepath.Join(tmpDir, "PDP.html"), "<p>edited</p>")
+
+	if _, err := svc.Generate(context.Background(), &models.RequestBody{Code: "PDP", Format: "pdf", Data: map[string]any{"name": "Aigerim"}}); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	result, err := auditLog.Query(context.Background(), models.AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Records) != 1 {
+		t.Fatalf("got %d records, want 1", len(result.Records))
+	}
+	listing := audit.SHA256(footer) + "  PDP.footer.html\n" + audit.SHA256(page) + "  PDP.html\n"
+	if got, want := result.Records[0].TemplateVersion, audit.SHA256([]byte(listing)); got != want {
+		t.Errorf("template version = %s, want %s of the rendered page and footer", got, want)
+	}
+}
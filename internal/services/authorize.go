@@ -0,0 +1,65 @@
+package services
+
+import (
+	"RBKproject4/internal/clients"
+	"RBKproject4/internal/reqctx"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden reports a client using a template or format it isn't allowed
+// to.
+var ErrForbidden = errors.New("forbidden")
+
+// WithClientRegistry limits every client to the templates and formats the
+// registry allows it. Without a registry every caller may use everything.
+func WithClientRegistry(registry *clients.Registry) Option {
+	return func(s *DocumentService) {
+		s.clients = registry
+	}
+}
+
+// client returns the registry entry of the client in ctx.
+func (s *DocumentService) client(ctx context.Context) (*clients.Client, error) {
+	id := reqctx.ClientID(ctx)
+	c, ok := s.clients.Client(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown client %q", ErrForbidden, id)
+	}
+	return c, nil
+}
+
+// authorize checks that the client in ctx may use the template code, and
+// generate format unless it is empty. It runs before anything about the
+// template is looked up, so that clients learn nothing of other templates.
+func (s *DocumentService) authorize(ctx context.Context, code, format string) error {
+	if s.clients == nil {
+		return nil
+	}
+	c, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	if !c.AllowsTemplate(code) {
+		return fmt.Errorf("%w: client %q may not use template %s", ErrForbidden, c.ID, code)
+	}
+	if format != "" && !c.AllowsFormat(format) {
+		return fmt.Errorf("%w: client %q may not generate %s", ErrForbidden, c.ID, format)
+	}
+	return nil
+}
+
+// mayAccessJob reports whether the client in ctx may see a job submitted by
+// owner: only its own, unless it is an admin.
+func (s *DocumentService) mayAccessJob(ctx context.Context, owner string) bool {
+	id := reqctx.ClientID(ctx)
+	if id == owner {
+		return true
+	}
+	if s.clients == nil {
+		return false
+	}
+	c, ok := s.clients.Client(id)
+	return ok && c.Admin
+}
@@ -0,0 +1,162 @@
+package services_test
+
+import (
+	"RBKproject4/internal/clients"
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/reqctx"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuthorization(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"CARD_STATEMENT.html", "PDP.html"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("<p></p>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := &upstreams{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	registry, err := clients.Parse([]byte(`
+clients:
+  - id: mobile
+    enabled: true
+    templates: ["CARD_*"]
+    formats: ["pdf"]
+  - id: retired
+    enabled: false
+    templates: ["*"]
+    formats: ["*"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, srv.Client(),
+		services.WithClientRegistry(registry))
+	mobile := reqctx.WithClientID(context.Background(), "mobile")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     models.RequestBody
+		wantErr error
+	}{
+		{"allowed", mobile, models.RequestBody{Code: "CARD_STATEMENT", Format: "pdf"}, nil},
+		{"template", mobile, models.RequestBody{Code: "PDP", Format: "pdf"}, services.ErrForbidden},
+		{"format", mobile, models.RequestBody{Code: "CARD_STATEMENT", Format: "html"}, services.ErrForbidden},
+		// a missing template isn't told apart from a forbidden one
+		{"missing template", mobile, models.RequestBody{Code: "NOPE", Format: "pdf"}, services.ErrForbidden},
+		{"disabled client", reqctx.WithClientID(context.Background(), "retired"), models.RequestBody{Code: "PDP", Format: "pdf"}, services.ErrForbidden},
+		{"no client", context.Background(), models.RequestBody{Code: "PDP", Format: "pdf"}, services.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.routes = nil
+			_, err := svc.Generate(tt.ctx, &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(fake.routes) != 0 {
+				t.Errorf("forbidden request reached %v", fake.routes)
+			}
+		})
+	}
+
+	if _, err := svc.GetTemplate(mobile, "PDP"); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("GetTemplate() error = %v, want ErrForbidden", err)
+	}
+	if err := svc.Validate(mobile, &models.RequestBody{Code: "PDP"}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Validate() error = %v, want ErrForbidden", err)
+	}
+
+	list, err := svc.ListTemplates(mobile)
+	if err != nil {
+		t.Fatalf("ListTemplates() error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "CARD_STATEMENT" {
+		t.Errorf("ListTemplates() = %v, want only CARD_STATEMENT", list)
+	}
+
+	jobSvc := services.NewJobService(logger, svc, jobs.NewMemoryStore(), 1, 1)
+	defer jobSvc.Close(context.Background())
+	if _, err := jobSvc.Submit(mobile, &models.RequestBody{Code: "CARD_STATEMENT", Format: "html"}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Submit() error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestJobService_OnlyOwnerSeesJob(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello {{ name }}!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := clients.Parse([]byte(`
+clients:
+  - id: mobile
+    enabled: true
+    templates: ["*"]
+    formats: ["*"]
+  - id: web
+    enabled: true
+    templates: ["*"]
+    formats: ["*"]
+  - id: ops
+    enabled: true
+    admin: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil,
+		services.WithClientRegistry(registry))
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 10)
+	defer svc.Close(context.Background())
+
+	mobile := reqctx.WithClientID(context.Background(), "mobile")
+	job, err := svc.Submit(mobile, &models.RequestBody{Code: "greet", Format: "html", Data: map[string]any{"name": "World"}})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	for {
+		if job, err = svc.Get(mobile, job.ID); err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if job.Finished() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	web := reqctx.WithClientID(context.Background(), "web")
+	if _, err := svc.Get(web, job.ID); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Get() by another client error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Result(web, job.ID); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Result() by another client error = %v, want ErrNotFound", err)
+	}
+
+	ops := reqctx.WithClientID(context.Background(), "ops")
+	if _, err := svc.Result(ops, job.ID); err != nil {
+		t.Errorf("Result() by an admin error: %v", err)
+	}
+	if _, err := svc.Result(mobile, job.ID); err != nil {
+		t.Errorf("Result() by the owner error: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const batchManifestName = "manifest.json"
+
+type batchOutcome struct {
+	index int
+	doc   *models.Document
+	err   error
+}
+
+func (s *DocumentService) MaxBatchItems() int {
+	return s.batchMaxItems
+}
+
+func (s *DocumentService) BatchTimeout() time.Duration {
+	return s.batchTimeout
+}
+
+// GenerateBatch renders every item with at most batchParallelism generations
+// in flight and streams them into a ZIP archive written to w, one entry per
+// successful item plus a manifest.json describing all of them. A failing item
+// is only recorded in the manifest; the returned error is reserved for
+// failures to write the archive itself.
+func (s *DocumentService) GenerateBatch(ctx context.Context, items []models.RequestBody, w io.Writer) (_ *models.BatchManifest, err error) {
+	ctx, span := startSpan(ctx, "DocumentService.GenerateBatch", attribute.Int("batch.items", len(items)))
+	defer func() { endSpan(span, err) }()
+
+	manifest := &models.BatchManifest{
+		Total: len(items),
+		Items: make([]models.BatchItemResult, len(items)),
+	}
+
+	outcomes := make(chan batchOutcome)
+	sem := make(chan struct{}, s.batchParallelism)
+	var wg sync.WaitGroup
+
+	go func() {
+		for i := range items {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				outcomes <- batchOutcome{index: i, err: ctx.Err()}
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				doc, err := s.Generate(ctx, &items[i])
+				outcomes <- batchOutcome{index: i, doc: doc, err: err}
+			}(i)
+		}
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	zw := zip.NewWriter(w)
+	var writeErr error
+
+	for outcome := range outcomes {
+		item := &items[outcome.index]
+		result := models.BatchItemResult{
+			Index:  outcome.index,
+			Code:   item.Code,
+			Format: item.Format,
+		}
+
+		switch {
+		case outcome.err != nil:
+			result.Error = ErrorDetail(outcome.err)
+			s.logger.WarnContext(ctx, "batch item failed", "index", outcome.index, "code", item.Code, "error", outcome.err)
+		case writeErr != nil:
+			// keep draining so the generating goroutines can finish
+			result.Error = "archive write failed"
+		default:
+			sum := sha256.Sum256(outcome.doc.Data)
+			result.File = batchEntryName(outcome.index, len(items), item.Code, outcome.doc.Filename)
+			result.SHA256 = hex.EncodeToString(sum[:])
+			result.Size = len(outcome.doc.Data)
+
+			if err := writeZipEntry(zw, result.File, outcome.doc.Data); err != nil {
+				writeErr = err
+				result.File = ""
+				result.Error = "archive write failed"
+				break
+			}
+			result.Success = true
+		}
+
+		if result.Success {
+			manifest.Succeeded++
+		} else {
+			manifest.Failed++
+		}
+		manifest.Items[outcome.index] = result
+	}
+
+	if writeErr != nil {
+		return manifest, writeErr
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("error marshaling manifest: %w", err)
+	}
+	if err := writeZipEntry(zw, batchManifestName, manifestJSON); err != nil {
+		return manifest, err
+	}
+	if err := zw.Close(); err != nil {
+		return manifest, fmt.Errorf("error closing archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// batchEntryName numbers entries so they sort in request order and two items
+// with the same code never collide.
+func batchEntryName(index, total int, code, filename string) string {
+	width := len(fmt.Sprint(total))
+	return fmt.Sprintf("%0*d_%s%s", width, index+1, code, filepath.Ext(filename))
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating archive entry %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("error writing archive entry %s: %w", name, err)
+	}
+	return nil
+}
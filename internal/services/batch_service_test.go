@@ -0,0 +1,86 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateBatch_PartialFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello {{ name }}!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil,
+		services.WithBatchLimits(2, 10))
+
+	items := []models.RequestBody{
+		{Code: "greet", Format: "html", Data: map[string]any{"name": "Alice"}},
+		{Code: "missing", Format: "html"},
+		{Code: "greet", Format: "html", Data: map[string]any{"name": "Bob"}},
+		{Code: "greet", Format: "odt"},
+	}
+
+	buf := &bytes.Buffer{}
+	manifest, err := svc.GenerateBatch(context.Background(), items, buf)
+	if err != nil {
+		t.Fatalf("GenerateBatch() error: %v", err)
+	}
+	if manifest.Succeeded != 2 || manifest.Failed != 2 {
+		t.Errorf("succeeded/failed = %d/%d, want 2/2", manifest.Succeeded, manifest.Failed)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	entries := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = data
+	}
+
+	if got := string(entries["1_greet.html"]); got != "Hello Alice!" {
+		t.Errorf("1_greet.html = %q", got)
+	}
+	if got := string(entries["3_greet.html"]); got != "Hello Bob!" {
+		t.Errorf("3_greet.html = %q", got)
+	}
+
+	var archived models.BatchManifest
+	if err := json.Unmarshal(entries["manifest.json"], &archived); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	if len(archived.Items) != len(items) {
+		t.Fatalf("manifest has %d items, want %d", len(archived.Items), len(items))
+	}
+
+	sum := sha256.Sum256([]byte("Hello Alice!"))
+	if first := archived.Items[0]; !first.Success || first.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("item 0 = %+v", first)
+	}
+	for _, i := range []int{1, 3} {
+		if item := archived.Items[i]; item.Success || item.Error == "" || item.File != "" {
+			t.Errorf("item %d = %+v, want a recorded failure", i, item)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var pdfcpuOnce sync.Once
+
+// pdfcpuConfig returns a configuration that does not touch the disk; by default
+// pdfcpu writes a config.yml to the user's config dir and exits the process
+// when it cannot.
+func pdfcpuConfig() *model.Configuration {
+	pdfcpuOnce.Do(func() {
+		model.ConfigPath = "disable"
+	})
+	return model.NewDefaultConfiguration()
+}
+
+func (s *DocumentService) ComposeTimeout() time.Duration {
+	return s.composeTimeout
+}
+
+// composeAuditCode is the code merged documents are audited under.
+const composeAuditCode = "compose"
+
+// Compose renders every part to PDF in parallel and merges them, in request
+// order, into one document with an outline entry per part.
+func (s *DocumentService) Compose(ctx context.Context, req *models.ComposeRequest) (_ *models.Document, err error) {
+	ctx, span := startSpan(ctx, "DocumentService.Compose", attribute.Int("compose.parts", len(req.Parts)))
+	defer func() { endSpan(span, err) }()
+
+	if len(req.Parts) == 0 {
+		return nil, fmt.Errorf("%w: compose request has no parts", ErrInvalidData)
+	}
+	if len(req.Parts) > s.composeMaxParts {
+		return nil, fmt.Errorf("%w: compose request has %d parts, at most %d are allowed", ErrInvalidData, len(req.Parts), s.composeMaxParts)
+	}
+
+	pdfs := make([][]byte, len(req.Parts))
+	warnings := make([][]string, len(req.Parts))
+	errs := make([]error, len(req.Parts))
+	strict := s.isStrict(req.StrictVariables)
+	sem := make(chan struct{}, s.batchParallelism)
+	var wg sync.WaitGroup
+
+	for i := range req.Parts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pdfs[i], warnings[i], errs[i] = s.renderPartPDF(ctx, &req.Parts[i], strict)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("error rendering part %d (%s): %w", i+1, req.Parts[i].Code, err)
+		}
+	}
+
+	bookmarks := make([]pdfcpu.Bookmark, len(req.Parts))
+	page := 1
+	for i, data := range pdfs {
+		count, err := api.PageCount(bytes.NewReader(data), pdfcpuConfig())
+		if err != nil {
+			return nil, fmt.Errorf("error counting pages of part %d: %w", i+1, err)
+		}
+		title := req.Parts[i].Title
+		if title == "" {
+			title = req.Parts[i].Code
+		}
+		bookmarks[i] = pdfcpu.Bookmark{Title: title, PageFrom: page}
+		page += count
+	}
+
+	merged := pdfs[0]
+	if len(pdfs) > 1 {
+		files := make([]formFile, len(pdfs))
+		for i, data := range pdfs {
+			// Gotenberg merges files in alphanumeric order of their names
+			files[i] = formFile{name: fmt.Sprintf("%03d.pdf", i+1), data: data}
+		}
+
+		var err error
+		merged, err = s.postToGotenberg(ctx, gotenbergMergeRoute, files, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error merging pdfs: %w", err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := api.AddBookmarks(bytes.NewReader(merged), out, bookmarks, true, pdfcpuConfig()); err != nil {
+		return nil, fmt.Errorf("error adding bookmarks: %w", err)
+	}
+
+	// the parts are recorded as they are generated, the merged document
+	// under the code "compose"
+	if err := s.recordAudit(ctx, composeAuditCode, "", "pdf", req.Parts, out.Bytes()); err != nil {
+		return nil, err
+	}
+
+	filename := req.Filename
+	if filename == "" {
+		filename = "document"
+	}
+	if !strings.HasSuffix(strings.ToLower(filename), ".pdf") {
+		filename += ".pdf"
+	}
+
+	return &models.Document{
+		Data:     out.Bytes(),
+		Format:   models.FormatPDF,
+		Filename: filename,
+		Warnings: mergeWarnings(warnings),
+	}, nil
+}
+
+func (s *DocumentService) renderPartPDF(ctx context.Context, part *models.ComposePart, strict bool) ([]byte, []string, error) {
+	opts := part.PDF
+	if part.Landscape {
+		landscape := true
+		opts = opts.Merge(&models.PDFOptions{Landscape: &landscape})
+	}
+
+	doc, err := s.generate(ctx, &models.RequestBody{
+		Code:            part.Code,
+		Format:          "pdf",
+		Data:            part.Data,
+		PDF:             opts,
+		StrictVariables: &strict,
+	}, "pdf")
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc.Data, doc.Warnings, nil
+}
+
+// mergeWarnings joins the warnings of all parts, keeping the first occurrence
+// of each.
+func mergeWarnings(parts [][]string) []string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, warnings := range parts {
+		for _, w := range warnings {
+			if !seen[w] {
+				seen[w] = true
+				merged = append(merged, w)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,181 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// minimalPDF builds a valid PDF with the given number of blank pages.
+func minimalPDF(pages int) []byte {
+	var objs []string
+	kids := make([]string, pages)
+	for i := 0; i < pages; i++ {
+		kids[i] = fmt.Sprintf("%d 0 R", i+3)
+	}
+	objs = append(objs, "<< /Type /Catalog /Pages 2 0 R >>")
+	objs = append(objs, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	for i := 0; i < pages; i++ {
+		objs = append(objs, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>")
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, obj := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return buf.Bytes()
+}
+
+func fakeGotenberg(t *testing.T) *httptest.Server {
+	model.ConfigPath = "disable"
+	conf := model.NewDefaultConfiguration()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Errorf("invalid multipart form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/forms/chromium/convert/html":
+			// landscape parts come back with two pages so the outline offsets
+			// can be checked
+			if r.FormValue("landscape") == "true" {
+				w.Write(minimalPDF(2))
+			} else {
+				w.Write(minimalPDF(1))
+			}
+		case "/forms/pdfengines/merge":
+			files := r.MultipartForm.File["files"]
+			sort.Slice(files, func(i, j int) bool { return files[i].Filename < files[j].Filename })
+			var readers []io.ReadSeeker
+			for _, fh := range files {
+				f, _ := fh.Open()
+				data, _ := io.ReadAll(f)
+				f.Close()
+				readers = append(readers, bytes.NewReader(data))
+			}
+			if err := api.MergeRaw(readers, w, false, conf); err != nil {
+				t.Errorf("merge failed: %v", err)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCompose_MergesPartsWithOutline(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"PDP.html", "CREDIT_SCHEDULE.html"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("<p>{{ clientName }}</p>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gotenberg := fakeGotenberg(t)
+	defer gotenberg.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, gotenberg.URL, gotenberg.Client())
+
+	doc, err := svc.Compose(context.Background(), &models.ComposeRequest{
+		Filename: "dossier",
+		Parts: []models.ComposePart{
+			{Code: "PDP", Title: "Заявление", Data: map[string]any{"clientName": "A"}},
+			{Code: "CREDIT_SCHEDULE", Landscape: true, Data: map[string]any{"clientName": "A"}},
+			{Code: "PDP", Data: map[string]any{"clientName": "A"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compose() error: %v", err)
+	}
+	if doc.Filename != "dossier.pdf" || doc.Format != models.FormatPDF {
+		t.Errorf("doc = %s (%s)", doc.Filename, doc.Format)
+	}
+
+	conf := model.NewDefaultConfiguration()
+	pages, err := api.PageCount(bytes.NewReader(doc.Data), conf)
+	if err != nil {
+		t.Fatalf("merged document is not a valid pdf: %v", err)
+	}
+	if pages != 4 {
+		t.Errorf("merged document has %d pages, want 4", pages)
+	}
+
+	bookmarks, err := api.Bookmarks(bytes.NewReader(doc.Data), conf)
+	if err != nil {
+		t.Fatalf("Bookmarks() error: %v", err)
+	}
+
+	want := []struct {
+		title string
+		page  int
+	}{{"Заявление", 1}, {"CREDIT_SCHEDULE", 2}, {"PDP", 4}}
+	if len(bookmarks) != len(want) {
+		t.Fatalf("got %d bookmarks, want %d", len(bookmarks), len(want))
+	}
+	for i, w := range want {
+		if bookmarks[i].Title != w.title || bookmarks[i].PageFrom != w.page {
+			t.Errorf("bookmark %d = %q@%d, want %q@%d", i, bookmarks[i].Title, bookmarks[i].PageFrom, w.title, w.page)
+		}
+	}
+}
+
+func TestCompose_MissingTemplate(t *testing.T) {
+	gotenberg := fakeGotenberg(t)
+	defer gotenberg.Close()
+
+	tmpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, gotenberg.URL, gotenberg.Client())
+
+	_, err := svc.Compose(context.Background(), &models.ComposeRequest{
+		Parts: []models.ComposePart{{Code: "NOPE"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "NOPE") {
+		t.Errorf("Compose() error = %v, want a missing template error", err)
+	}
+}
+
+func TestCompose_TooManyParts(t *testing.T) {
+	gotenberg := fakeGotenberg(t)
+	defer gotenberg.Close()
+
+	tmpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, gotenberg.URL, gotenberg.Client(),
+		services.WithComposeLimits(2, 0))
+
+	_, err := svc.Compose(context.Background(), &models.ComposeRequest{
+		Parts: []models.ComposePart{{Code: "A"}, {Code: "B"}, {Code: "C"}},
+	})
+	if !errors.Is(err, services.ErrInvalidData) || !strings.Contains(err.Error(), "at most 2") {
+		t.Errorf("Compose() error = %v, want invalid data naming the limit", err)
+	}
+}
@@ -1,19 +1,36 @@
 package services
 
 import (
+	"RBKproject4/internal/audit"
+	"RBKproject4/internal/clients"
+	"RBKproject4/internal/metrics"
 	"RBKproject4/internal/models"
 	"RBKproject4/internal/renderers"
-	"bytes"
+	"RBKproject4/internal/upstream"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	ErrTemplateNotFound  = errors.New("template not found")
+	// ErrRenderFailed reports a template that could not be rendered, here or
+	// by the service rendering it.
+	ErrRenderFailed = errors.New("render failed")
+	// ErrUpstreamUnavailable reports the Python service or Gotenberg being
+	// unreachable or failing.
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrUpstreamTimeout reports the Python service or Gotenberg not answering
+	// in time.
+	ErrUpstreamTimeout = errors.New("upstream timeout")
 )
 
 type DocumentService struct {
@@ -21,21 +38,112 @@ type DocumentService struct {
 	pythonURL        string
 	templateDir      string
 	gotenbergURL     string
-	gotenbergPDFURL  string
-	client           *http.Client
+	python           *upstream.Client
+	gotenberg        *upstream.Client
+	metrics          *metrics.Metrics
+	audit            *audit.Log
+	clients          *clients.Registry
 	logger           *slog.Logger
+	batchParallelism int
+	batchMaxItems    int
+	batchTimeout     time.Duration
+	composeMaxParts  int
+	composeTimeout   time.Duration
+	strictVariables  bool
+	templates        *renderers.Registry
+	nativeRenderers  map[string]renderers.DocumentRenderer
+
+	generators map[string]map[string]generator // by source, then output format
+	sources    []string                        // source types in order of preference
+}
+
+type Option func(*DocumentService)
+
+// WithBatchLimits caps how many items of a batch are generated at once and how
+// many items a single batch may contain.
+func WithBatchLimits(parallelism, maxItems int) Option {
+	return func(s *DocumentService) {
+		if parallelism > 0 {
+			s.batchParallelism = parallelism
+		}
+		if maxItems > 0 {
+			s.batchMaxItems = maxItems
+		}
+	}
+}
+
+// WithBatchTimeout limits how long generating and streaming one batch may
+// take.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(s *DocumentService) {
+		if d > 0 {
+			s.batchTimeout = d
+		}
+	}
+}
+
+// WithComposeLimits caps how many parts one compose request may merge and how
+// long rendering and merging them may take.
+func WithComposeLimits(maxParts int, timeout time.Duration) Option {
+	return func(s *DocumentService) {
+		if maxParts > 0 {
+			s.composeMaxParts = maxParts
+		}
+		if timeout > 0 {
+			s.composeTimeout = timeout
+		}
+	}
+}
+
+// WithTemplateRegistry serves DOCX and XLSX templates from registry instead of
+// reading them from disk for every request.
+func WithTemplateRegistry(registry *renderers.Registry) Option {
+	return func(s *DocumentService) {
+		s.templates = registry
+	}
+}
+
+// WithUpstreams sends requests to the Python service and Gotenberg through
+// the given clients instead of plain ones made from the service's
+// http.Client, so that they are retried and guarded by breakers.
+func WithUpstreams(python, gotenberg *upstream.Client) Option {
+	return func(s *DocumentService) {
+		s.python = python
+		s.gotenberg = gotenberg
+	}
 }
 
-func NewDocumentService(logger *slog.Logger, templateRenderer renderers.TemplateRenderer, pythonURL, templateDir, gotenbergURL string, client *http.Client) *DocumentService {
-	return &DocumentService{
+// WithMetrics records every generation and template render in m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *DocumentService) {
+		s.metrics = m
+	}
+}
+
+func NewDocumentService(logger *slog.Logger, templateRenderer renderers.TemplateRenderer, pythonURL, templateDir, gotenbergURL string, client *http.Client, opts ...Option) *DocumentService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &DocumentService{
 		logger:           logger,
 		pythonURL:        pythonURL,
 		templateDir:      templateDir,
 		templateRenderer: templateRenderer,
 		gotenbergURL:     gotenbergURL,
-		gotenbergPDFURL:  gotenbergURL + "/forms/chromium/convert/html",
-		client:           client,
+		python:           upstream.New(upstreamPython, client, upstream.Options{}),
+		gotenberg:        upstream.New(upstreamGotenberg, client, upstream.Options{}),
+		batchParallelism: 4,
+		batchMaxItems:    500,
+		batchTimeout:     10 * time.Minute,
+		composeMaxParts:  50,
+		composeTimeout:   5 * time.Minute,
 	}
+	s.registerBuiltinGenerators()
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func ToMap(data any) (map[string]interface{}, error) {
@@ -50,87 +158,181 @@ func ToMap(data any) (map[string]interface{}, error) {
 	return m, nil
 }
 
+// Generate renders req in whichever output format it asks for.
+func (s *DocumentService) Generate(ctx context.Context, req *models.RequestBody) (*models.Document, error) {
+	return s.generate(ctx, req, req.Format)
+}
+
+// GeneratePDF renders the template of req.Code and converts it to PDF. The
+// source is picked from the template files present: HTML goes through
+// Chromium, DOCX and XLSX are rendered and converted through LibreOffice.
 func (s *DocumentService) GeneratePDF(ctx context.Context, req *models.RequestBody) (*models.Document, error) {
-	dataMap, err := ToMap(req.Data)
-	if err != nil {
-		return nil, fmt.Errorf("error converting data to map: %w", err)
+	return s.generate(ctx, req, "pdf")
+}
+
+// templateFile returns the contents of the template file <code>.<ext>.
+func (s *DocumentService) templateFile(code, ext string) ([]byte, error) {
+	name := code + "." + ext
+	if s.templates != nil {
+		return s.templates.Source(name)
 	}
+	return os.ReadFile(filepath.Join(s.templateDir, name))
+}
+
+func (s *DocumentService) templateExists(code, ext string) bool {
+	info, err := os.Stat(filepath.Join(s.templateDir, code+"."+ext))
+	return err == nil && !info.IsDir()
+}
 
-	renderedHTML, err := s.templateRenderer.Render(req.Code, dataMap)
+// pdfOptions returns the PDF options of the template's manifest with override
+// applied on top.
+func (s *DocumentService) pdfOptions(code string, override *models.PDFOptions) (*models.PDFOptions, error) {
+	manifest, err := s.loadManifest(code)
 	if err != nil {
-		return nil, fmt.Errorf("error rendering html: %w", err)
+		return nil, err
+	}
+	opts := manifest.PDF.Merge(override)
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: invalid pdf options: %w", ErrInvalidData, err)
 	}
+	return opts, nil
+}
 
-	buf := &bytes.Buffer{}
-	writer := multipart.NewWriter(buf)
-	part, err := writer.CreateFormFile("files", "index.html")
+// generateHTMLPDF renders the HTML template of req and converts it with
+// Chromium.
+func (s *DocumentService) generateHTMLPDF(ctx context.Context, req *models.RequestBody, dataMap map[string]interface{}) ([]byte, error) {
+	opts, err := s.pdfOptions(req.Code, req.PDF)
 	if err != nil {
-		return nil, fmt.Errorf("error creating form file: %w", err)
+		return nil, err
 	}
 
-	_, err = part.Write([]byte(renderedHTML))
+	renderedHTML, err := s.renderHTML(ctx, req.Code, dataMap)
 	if err != nil {
-		return nil, fmt.Errorf("error writing to form file: %w", err)
+		return nil, s.renderError(ctx, req.Code, err)
 	}
 
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("error closing form file: %w", err)
+	files, err := s.htmlWithAssets(ctx, req.Code, renderedHTML)
+	if err != nil {
+		return nil, err
 	}
 
-	newReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.gotenbergPDFURL, buf)
+	headerFooter, err := s.renderHeaderFooter(ctx, req.Code, dataMap, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	newReq.Header.Set("Content-Type", writer.FormDataContentType())
+	return s.postToGotenberg(ctx, gotenbergChromiumHTMLRoute, append(files, headerFooter...), opts.ChromiumFields())
+}
 
-	resp, err := s.client.Do(newReq)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
+// officePDFGenerator renders the DOCX or XLSX template of a request and
+// converts it with LibreOffice.
+func (s *DocumentService) officePDFGenerator(source string) GeneratorFunc {
+	return func(ctx context.Context, req *models.RequestBody, dataMap map[string]interface{}) ([]byte, error) {
+		opts, err := s.pdfOptions(req.Code, req.PDF)
+		if err != nil {
+			return nil, err
+		}
+
+		office, err := s.renderOffice(ctx, req.Code, source, dataMap, req.Engine)
+		if err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("gotenberg returned status %d", resp.StatusCode)
+		return s.postToGotenberg(ctx, gotenbergLibreOfficeRoute, []formFile{{name: req.Code + "." + source, data: office}}, opts.LibreOfficeFields())
 	}
+}
 
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			s.logger.Warn("failed to close response body")
+// renderHeaderFooter renders the header and footer templates with the same
+// data as the page. Unless the options name other templates, <CODE>.header.html
+// and <CODE>.footer.html are used when they exist.
+func (s *DocumentService) renderHeaderFooter(ctx context.Context, code string, dataMap map[string]interface{}, opts *models.PDFOptions) ([]formFile, error) {
+	var files []formFile
+	for _, part := range []struct{ name, template string }{
+		{"header", opts.Header},
+		{"footer", opts.Footer},
+	} {
+		template := part.template
+		if template == "" {
+			template = code + "." + part.name
+			if !s.templateExists(template, "html") {
+				continue
+			}
+		} else if err := s.checkHeaderFooter(ctx, code, template); err != nil {
+			return nil, err
 		}
-	}()
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		rendered, err := s.renderHTML(ctx, template, dataMap)
+		if err != nil {
+			return nil, s.renderError(ctx, part.name+" of "+code, err)
+		}
+		files = append(files, formFile{name: part.name + ".html", data: []byte(rendered)})
 	}
-
-	return &models.Document{
-		Data:     data,
-		Format:   models.FormatPDF,
-		Filename: "document.pdf",
-	}, nil
+	return files, nil
 }
 
-func (s *DocumentService) GenerateHTML(_ context.Context, req *models.RequestBody) (*models.Document, error) {
-	dataMap, err := ToMap(req.Data)
+// checkHeaderFooter checks a header or footer template named in the PDF
+// options, which may come from the request: it must be the code's own
+// <CODE>.header or <CODE>.footer or one its manifest declares, and the client
+// must be allowed to use the template it belongs to.
+func (s *DocumentService) checkHeaderFooter(ctx context.Context, code, name string) error {
+	manifest, err := s.loadManifest(code)
 	if err != nil {
-		return nil, fmt.Errorf("error converting data to map: %w", err)
+		return err
 	}
+	declared := name == code+".header" || name == code+".footer" ||
+		(manifest.PDF != nil && (name == manifest.PDF.Header || name == manifest.PDF.Footer))
+	if !declared || !validHeaderFooter.MatchString(name) {
+		return fmt.Errorf("%w: %q is not a header or footer of %s", ErrInvalidData, name, code)
+	}
+	owner := strings.TrimSuffix(strings.TrimSuffix(name, ".header"), ".footer")
+	return s.authorize(ctx, owner, "")
+}
 
-	renderedHTML, err := s.templateRenderer.Render(req.Code, dataMap)
-	if err != nil {
-		return nil, fmt.Errorf("error rendering html: %w", err)
+// renderHTML renders an HTML template with pongo2.
+func (s *DocumentService) renderHTML(ctx context.Context, name string, dataMap map[string]interface{}) (_ string, err error) {
+	_, span := startSpan(ctx, "pongo2.Render", attrTemplateName.String(name))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	defer func() { s.metrics.ObserveRender("pongo2", time.Since(start)) }()
+	if renderer, ok := s.templateRenderer.(renderers.SourceRenderer); ok {
+		out, source, err := renderer.RenderSource(name, dataMap)
+		if err == nil {
+			addTemplateSource(ctx, name+".html", source)
+		}
+		return out, err
 	}
+	return s.templateRenderer.Render(name, dataMap)
+}
 
-	return &models.Document{
-		Data:     []byte(renderedHTML),
-		Format:   models.FormatHTML,
-		Filename: "document.html",
-	}, nil
+func (s *DocumentService) GenerateHTML(ctx context.Context, req *models.RequestBody) (*models.Document, error) {
+	return s.generate(ctx, req, "html")
 }
 
-func (s *DocumentService) ListTemplates(_ context.Context) ([]*models.Template, error) {
+func (s *DocumentService) generateHTML(ctx context.Context, req *models.RequestBody, dataMap map[string]interface{}) ([]byte, error) {
+	renderedHTML, err := s.renderHTML(ctx, req.Code, dataMap)
+	if err != nil {
+		return nil, s.renderError(ctx, req.Code, err)
+	}
+	return []byte(renderedHTML), nil
+}
+
+// ListTemplates returns one entry per template file, with the metadata of the
+// code's manifest when it has one and the formats the code can be generated in.
+func (s *DocumentService) ListTemplates(ctx context.Context) (_ []*models.Template, err error) {
+	_, span := startSpan(ctx, "DocumentService.ListTemplates")
+	defer func() { endSpan(span, err) }()
+
+	var client *clients.Client
+	if s.clients != nil {
+		if client, err = s.client(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	result := make([]*models.Template, 0)
+	manifests := map[string]*models.TemplateManifest{}
+	outputs := map[string][]string{}
 
 	templates, err := os.ReadDir(s.templateDir)
 	if err != nil {
@@ -142,9 +344,39 @@ func (s *DocumentService) ListTemplates(_ context.Context) ([]*models.Template,
 		}
 		extension := filepath.Ext(file.Name())
 		filename := strings.TrimSuffix(file.Name(), extension)
+		// manifests, headers and footers (CODE.manifest.yaml, CODE.header.html)
+		// belong to a template rather than being one
+		if strings.Contains(filename, ".") {
+			continue
+		}
 		extension = strings.TrimPrefix(extension, ".")
+		// clients only see the templates they may use
+		if client != nil && !client.AllowsTemplate(filename) {
+			continue
+		}
+
+		manifest, ok := manifests[filename]
+		if !ok {
+			manifest, err = s.loadManifest(filename)
+			if err != nil {
+				// one broken manifest must not hide every other template
+				s.logger.WarnContext(ctx, "failed to load manifest", "code", filename, "error", err)
+				manifest = &models.TemplateManifest{}
+			}
+			manifests[filename] = manifest
+			for _, output := range s.outputs(filename, manifest) {
+				outputs[filename] = append(outputs[filename], output.Format)
+			}
+		}
 
-		result = append(result, &models.Template{Name: filename, Format: extension})
+		result = append(result, &models.Template{
+			Name:    filename,
+			Format:  extension,
+			Title:   manifest.Title,
+			Owner:   manifest.Owner,
+			Formats: manifest.Formats,
+			Outputs: outputs[filename],
+		})
 	}
 
 	return result, nil
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	EnginePython = "python"
+	EngineNative = "native"
+)
+
+var ErrUnknownEngine = errors.New("unknown engine")
+
+// WithNativeRenderer renders templates of format in-process with renderer for
+// codes whose manifest, or request, selects the native engine.
+func WithNativeRenderer(format string, renderer renderers.DocumentRenderer) Option {
+	return func(s *DocumentService) {
+		if s.nativeRenderers == nil {
+			s.nativeRenderers = map[string]renderers.DocumentRenderer{}
+		}
+		s.nativeRenderers[format] = renderer
+	}
+}
+
+// officeGenerator renders the DOCX or XLSX template of a request as is.
+func (s *DocumentService) officeGenerator(source string) GeneratorFunc {
+	return func(ctx context.Context, req *models.RequestBody, dataMap map[string]interface{}) ([]byte, error) {
+		return s.renderOffice(ctx, req.Code, source, dataMap, req.Engine)
+	}
+}
+
+// renderOffice renders the DOCX or XLSX template of code with the engine the
+// request asks for, falling back to the one in the template's manifest.
+func (s *DocumentService) renderOffice(ctx context.Context, code, format string, dataMap map[string]interface{}, override string) ([]byte, error) {
+	engine := override
+	if engine == "" {
+		manifest, err := s.loadManifest(code)
+		if err != nil {
+			return nil, err
+		}
+		engine = manifest.Engine
+	}
+
+	switch engine {
+	case "", EnginePython:
+		return s.renderWithPython(ctx, code, format, dataMap)
+	case EngineNative:
+		renderer, ok := s.nativeRenderers[format]
+		if !ok {
+			return nil, fmt.Errorf("%w: no native engine for %s", ErrUnknownEngine, format)
+		}
+		_, span := startSpan(ctx, format+".Render", attrTemplateName.String(code+"."+format))
+		start := time.Now()
+		data, err := s.renderNative(ctx, renderer, code, format, dataMap)
+		s.metrics.ObserveRender(format, time.Since(start))
+		endSpan(span, err)
+		if err != nil {
+			return nil, s.renderError(ctx, code+"."+format, err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownEngine, engine)
+	}
+}
+
+// renderNative renders the office template of code in-process, recording the
+// source it was rendered from when the renderer tells.
+func (s *DocumentService) renderNative(ctx context.Context, renderer renderers.DocumentRenderer, code, format string, dataMap map[string]interface{}) ([]byte, error) {
+	withSource, ok := renderer.(renderers.SourceDocumentRenderer)
+	if !ok {
+		return renderer.Render(code, dataMap)
+	}
+	data, source, err := withSource.RenderSource(code, dataMap)
+	if err == nil {
+		addTemplateSource(ctx, code+"."+format, source)
+	}
+	return data, err
+}
@@ -0,0 +1,73 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+type fakeDocumentRenderer struct{}
+
+func (fakeDocumentRenderer) Render(templateName string, _ map[string]interface{}) ([]byte, error) {
+	return []byte("native " + templateName), nil
+}
+
+func TestGenerateDOCX_SelectsEngine(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "PDP.docx"), "docx")
+	writeFile(t, filepath.Join(tmpDir, "LOAN.docx"), "docx")
+	writeFile(t, filepath.Join(tmpDir, "LOAN.manifest.yaml"), "engine: native\n")
+
+	python := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("python"))
+	}))
+	defer python.Close()
+
+	svc := services.NewDocumentService(nil, nil, python.URL, tmpDir, "", python.Client(),
+		services.WithNativeRenderer("docx", fakeDocumentRenderer{}))
+
+	tests := []struct {
+		code    string
+		engine  string
+		want    string
+		wantErr error
+	}{
+		{code: "PDP", want: "python"},
+		{code: "LOAN", want: "native LOAN"},
+		{code: "PDP", engine: "native", want: "native PDP"},
+		{code: "LOAN", engine: "python", want: "python"},
+		{code: "LOAN", engine: "docxtpl", wantErr: services.ErrUnknownEngine},
+	}
+
+	for _, tt := range tests {
+		doc, err := svc.GenerateDOCX(context.Background(), &models.RequestBody{Code: tt.code, Format: "docx", Engine: tt.engine})
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s/%q: error = %v, want %v", tt.code, tt.engine, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s/%q: unexpected error: %v", tt.code, tt.engine, err)
+		}
+		if string(doc.Data) != tt.want {
+			t.Errorf("%s/%q: rendered by %q, want %q", tt.code, tt.engine, doc.Data, tt.want)
+		}
+	}
+}
+
+func TestGenerateXLSX_NativeEngineUnavailable(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "REPORT.xlsx"), "xlsx")
+
+	svc := services.NewDocumentService(nil, nil, "", tmpDir, "", nil)
+	_, err := svc.GenerateXLSX(context.Background(), &models.RequestBody{Code: "REPORT", Format: "xlsx", Engine: "native"})
+	if !errors.Is(err, services.ErrUnknownEngine) {
+		t.Errorf("error = %v, want ErrUnknownEngine", err)
+	}
+}
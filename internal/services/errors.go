@@ -0,0 +1,64 @@
+package services
+
+import (
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/upstream"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// internalErrorDetail stands in for errors whose message may carry internal
+// details such as file paths or upstream URLs.
+const internalErrorDetail = "internal error"
+
+// RenderError reports a template that failed to render in this service, as
+// opposed to being rejected by an upstream. Its cause can name template files
+// and echo the data, so Error leaves it out; it is logged instead.
+type RenderError struct {
+	Template string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("%v: %s could not be rendered", ErrRenderFailed, e.Template)
+}
+
+func (e *RenderError) Unwrap() []error {
+	return []error{ErrRenderFailed, e.Err}
+}
+
+// renderError logs why template failed to render and returns the
+// RenderError clients see.
+func (s *DocumentService) renderError(ctx context.Context, template string, err error) error {
+	s.logger.ErrorContext(ctx, "template render failed", "template", template, "error", err)
+	return &RenderError{Template: template, Err: err}
+}
+
+// ErrorDetail is the message of err that may be shown to clients, e.g. in a
+// batch manifest. The errors of known kinds are worded for clients; any other
+// error is replaced with a generic message.
+func ErrorDetail(err error) string {
+	var verr *ValidationError
+	var uerr *renderers.UndefinedVariablesError
+	var ferr *UnsupportedFormatError
+	var oerr *upstream.OpenError
+	switch {
+	case errors.As(err, &verr), errors.As(err, &uerr), errors.As(err, &ferr), errors.As(err, &oerr),
+		errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrTemplateNotFound),
+		errors.Is(err, ErrInvalidData),
+		errors.Is(err, ErrUnsupportedFormat),
+		errors.Is(err, ErrUnknownEngine),
+		errors.Is(err, ErrQueueFull),
+		errors.Is(err, ErrJobInterrupted),
+		errors.Is(err, ErrUpstreamTimeout),
+		errors.Is(err, ErrUpstreamUnavailable),
+		errors.Is(err, ErrRenderFailed),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return err.Error()
+	default:
+		return internalErrorDetail
+	}
+}
@@ -0,0 +1,25 @@
+package services_test
+
+import (
+	"RBKproject4/internal/services"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorDetail(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: PDP", services.ErrTemplateNotFound), "template not found: PDP"},
+		{fmt.Errorf("%w: pdfconverter did not answer in time", services.ErrUpstreamTimeout), "upstream timeout: pdfconverter did not answer in time"},
+		// anything else may name files or hosts
+		{fmt.Errorf("error saving job: %w", errors.New("open /var/lib/jobs/1.json: permission denied")), "internal error"},
+	}
+	for _, tt := range tests {
+		if got := services.ErrorDetail(tt.err); got != tt.want {
+			t.Errorf("ErrorDetail(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/reqctx"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// upstreams fakes the Python service and Gotenberg on one server, recording
+// which routes were hit and the name of the file sent to LibreOffice.
+type upstreams struct {
+	routes     []string
+	officeFile string
+}
+
+func (u *upstreams) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.routes = append(u.routes, r.URL.Path)
+	switch r.URL.Path {
+	case "/docx/render", "/xlsx/render":
+		w.Write([]byte("rendered-office-file"))
+	case "/forms/libreoffice/convert":
+		_, fh, err := r.FormFile("files")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u.officeFile = fh.Filename
+		w.Write([]byte("%PDF-office"))
+	case "/forms/chromium/convert/html":
+		w.Write([]byte("%PDF-html"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGeneratePDF_PicksSourceFromTemplateFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"HTML_ONLY.html", "DOCX_ONLY.docx", "XLSX_ONLY.xlsx", "BOTH.html", "BOTH.docx"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("template"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		code       string
+		wantRoutes []string
+		wantOffice string
+		wantBody   string
+	}{
+		{"HTML_ONLY", []string{"/forms/chromium/convert/html"}, "", "%PDF-html"},
+		{"DOCX_ONLY", []string{"/docx/render", "/forms/libreoffice/convert"}, "DOCX_ONLY.docx", "%PDF-office"},
+		{"XLSX_ONLY", []string{"/xlsx/render", "/forms/libreoffice/convert"}, "XLSX_ONLY.xlsx", "%PDF-office"},
+		{"BOTH", []string{"/forms/chromium/convert/html"}, "", "%PDF-html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			fake := &upstreams{}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, srv.Client())
+
+			doc, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: tt.code, Format: "pdf"})
+			if err != nil {
+				t.Fatalf("GeneratePDF() error: %v", err)
+			}
+
+			if string(doc.Data) != tt.wantBody {
+				t.Errorf("body = %q, want %q", doc.Data, tt.wantBody)
+			}
+			if doc.Filename != tt.code+".pdf" {
+				t.Errorf("filename = %q, want %q", doc.Filename, tt.code+".pdf")
+			}
+			if len(fake.routes) != len(tt.wantRoutes) {
+				t.Fatalf("routes = %v, want %v", fake.routes, tt.wantRoutes)
+			}
+			for i := range tt.wantRoutes {
+				if fake.routes[i] != tt.wantRoutes[i] {
+					t.Errorf("routes = %v, want %v", fake.routes, tt.wantRoutes)
+					break
+				}
+			}
+			if fake.officeFile != tt.wantOffice {
+				t.Errorf("file sent to LibreOffice = %q, want %q", fake.officeFile, tt.wantOffice)
+			}
+		})
+	}
+}
+
+func TestGeneratePDF_NoTemplate(t *testing.T) {
+	svc := services.NewDocumentService(nil, nil, "", t.TempDir(), "", nil)
+
+	_, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: "MISSING", Format: "pdf"})
+	if !errors.Is(err, services.ErrTemplateNotFound) {
+		t.Errorf("GeneratePDF() error = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestGeneratePDF_ForwardsRequestID(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "DOCX_ONLY.docx"), []byte("template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]http.Header{}
+	fake := &upstreams{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers[r.URL.Path] = r.Header.Clone()
+		fake.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, srv.Client())
+
+	ctx := reqctx.WithRequestID(context.Background(), "req-42")
+	if _, err := svc.GeneratePDF(ctx, &models.RequestBody{Code: "DOCX_ONLY", Format: "pdf"}); err != nil {
+		t.Fatalf("GeneratePDF() error: %v", err)
+	}
+
+	if got := headers["/docx/render"].Get("X-Request-ID"); got != "req-42" {
+		t.Errorf("python service got X-Request-ID %q, want req-42", got)
+	}
+	if got := headers["/forms/libreoffice/convert"].Get("Gotenberg-Trace"); got != "req-42" {
+		t.Errorf("gotenberg got Gotenberg-Trace %q, want req-42", got)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Generator renders a request into one output format from one type of
+// template file. data is the request data as a map, already validated
+// against the template's schema.
+type Generator interface {
+	Generate(ctx context.Context, req *models.RequestBody, data map[string]interface{}) ([]byte, error)
+}
+
+// GeneratorFunc adapts a function to Generator.
+type GeneratorFunc func(ctx context.Context, req *models.RequestBody, data map[string]interface{}) ([]byte, error)
+
+func (f GeneratorFunc) Generate(ctx context.Context, req *models.RequestBody, data map[string]interface{}) ([]byte, error) {
+	return f(ctx, req, data)
+}
+
+type generator struct {
+	Generator
+	contentType models.DocumentFormat
+}
+
+// UnsupportedFormatError reports an output format a template can't be
+// generated in, with the ones it can.
+type UnsupportedFormatError struct {
+	Code      string
+	Format    string
+	Supported []models.TemplateOutput
+	// Known is set when the format is generated for other templates.
+	Known bool
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("%s: %s is not available as %q", ErrUnsupportedFormat, e.Code, e.Format)
+}
+
+func (e *UnsupportedFormatError) Unwrap() error {
+	return ErrUnsupportedFormat
+}
+
+// WithGenerator registers g to generate output from templates of type source,
+// replacing the built-in generator for the pair if there is one. Documents it
+// generates are sent as contentType. Templates of a new source type are used
+// when a code has no file of the built-in types that can produce output.
+func WithGenerator(source, output string, contentType models.DocumentFormat, g Generator) Option {
+	return func(s *DocumentService) {
+		s.registerGenerator(source, output, contentType, g)
+	}
+}
+
+func (s *DocumentService) registerGenerator(source, output string, contentType models.DocumentFormat, g Generator) {
+	if s.generators == nil {
+		s.generators = map[string]map[string]generator{}
+	}
+	if s.generators[source] == nil {
+		s.generators[source] = map[string]generator{}
+		s.sources = append(s.sources, source)
+	}
+	s.generators[source][output] = generator{Generator: g, contentType: contentType}
+}
+
+// registerBuiltinGenerators registers HTML, PDF, DOCX and XLSX generation. The
+// order sets which template a code is generated from when it has several:
+// HTML before DOCX before XLSX.
+func (s *DocumentService) registerBuiltinGenerators() {
+	s.registerGenerator("html", "html", models.FormatHTML, GeneratorFunc(s.generateHTML))
+	s.registerGenerator("html", "pdf", models.FormatPDF, GeneratorFunc(s.generateHTMLPDF))
+	s.registerGenerator("docx", "docx", models.FormatDOCX, s.officeGenerator("docx"))
+	s.registerGenerator("docx", "pdf", models.FormatPDF, s.officePDFGenerator("docx"))
+	s.registerGenerator("xlsx", "xlsx", models.FormatXLSX, s.officeGenerator("xlsx"))
+	s.registerGenerator("xlsx", "pdf", models.FormatPDF, s.officePDFGenerator("xlsx"))
+}
+
+// outputs lists the formats code can be generated in, each with the template
+// file it is generated from, as allowed by its manifest.
+func (s *DocumentService) outputs(code string, manifest *models.TemplateManifest) []models.TemplateOutput {
+	var outputs []models.TemplateOutput
+	seen := map[string]bool{}
+	for _, source := range s.templateSources(code) {
+		formats := make([]string, 0, len(s.generators[source]))
+		for format := range s.generators[source] {
+			formats = append(formats, format)
+		}
+		sort.Strings(formats)
+
+		for _, format := range formats {
+			if seen[format] || !manifest.SupportsFormat(format) {
+				continue
+			}
+			seen[format] = true
+			outputs = append(outputs, models.TemplateOutput{
+				Source:      source,
+				Format:      format,
+				ContentType: string(s.generators[source][format].contentType),
+			})
+		}
+	}
+	return outputs
+}
+
+// generatorFor finds the generator that produces format for code, and the
+// type of template file it is generated from.
+func (s *DocumentService) generatorFor(code, format string, manifest *models.TemplateManifest) (string, generator, error) {
+	if !s.HasTemplate(code) {
+		return "", generator{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, code)
+	}
+
+	outputs := s.outputs(code, manifest)
+	for _, output := range outputs {
+		if output.Format == format {
+			return output.Source, s.generators[output.Source][format], nil
+		}
+	}
+
+	return "", generator{}, &UnsupportedFormatError{Code: code, Format: format, Supported: outputs, Known: s.KnowsFormat(format)}
+}
+
+// HasTemplate reports whether code names a template, which tells the codes
+// we serve from anything a client may send.
+func (s *DocumentService) HasTemplate(code string) bool {
+	return validCode.MatchString(code) && len(s.templateSources(code)) > 0
+}
+
+// KnowsFormat reports whether any template type can be generated in format.
+func (s *DocumentService) KnowsFormat(format string) bool {
+	for _, generators := range s.generators {
+		if _, ok := generators[format]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// generate renders req as format with the generator registered for it.
+func (s *DocumentService) generate(ctx context.Context, req *models.RequestBody, format string) (_ *models.Document, err error) {
+	ctx, span := startSpan(ctx, "DocumentService.Generate", attrTemplateCode.String(req.Code), attrFormat.String(format))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.authorize(ctx, req.Code, format); err != nil {
+		return nil, err
+	}
+	dataMap, err := ToMap(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data is not an object: %w", ErrInvalidData, err)
+	}
+
+	manifest, err := s.loadManifest(req.Code)
+	if err != nil {
+		return nil, err
+	}
+	source, gen, err := s.generatorFor(req.Code, format, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateData(req.Code, manifest.Schema, req.Data, req.Strict); err != nil {
+		return nil, err
+	}
+
+	warnings, err := s.checkVariables(ctx, req.Code, source, dataMap, s.isStrict(req.StrictVariables))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, _ = withTemplateSources(ctx)
+	done := s.metrics.StartGeneration(req.Code, format)
+	data, err := gen.Generate(ctx, req, dataMap)
+	done(len(data), err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAudit(ctx, req.Code, source, format, req.Data, data); err != nil {
+		return nil, err
+	}
+
+	return &models.Document{
+		Data:     data,
+		Format:   gen.contentType,
+		Filename: s.documentFilename(ctx, manifest, format, req.Code+"."+format, dataMap),
+		Warnings: warnings,
+	}, nil
+}
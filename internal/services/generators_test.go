@@ -0,0 +1,98 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newGeneratorFixture(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "PDP.docx"), "docx")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.html"), "<p>{{ name }}</p>")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.docx"), "docx")
+	writeFile(t, filepath.Join(tmpDir, "NOTICE.md"), "# {{ name }}")
+	writeFile(t, filepath.Join(tmpDir, "RESTRICTED.html"), "<p></p>")
+	writeFile(t, filepath.Join(tmpDir, "RESTRICTED.manifest.yaml"), "formats: [pdf]\n")
+	return tmpDir
+}
+
+func TestGenerate_CustomGenerator(t *testing.T) {
+	tmpDir := newGeneratorFixture(t)
+	markdown := services.GeneratorFunc(func(_ context.Context, req *models.RequestBody, data map[string]interface{}) ([]byte, error) {
+		return []byte("<h1>" + data["name"].(string) + "</h1>"), nil
+	})
+	svc := services.NewDocumentService(nil, nil, "", tmpDir, "", nil,
+		services.WithGenerator("md", "html", models.FormatHTML, markdown))
+
+	doc, err := svc.Generate(context.Background(), &models.RequestBody{Code: "NOTICE", Format: "html", Data: map[string]any{"name": "Notice"}})
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if string(doc.Data) != "<h1>Notice</h1>" || doc.ContentType() != "text/html" || doc.Filename != "NOTICE.html" {
+		t.Errorf("document = %q, %s, %s", doc.Data, doc.ContentType(), doc.Filename)
+	}
+}
+
+func TestGenerate_UnsupportedFormat(t *testing.T) {
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(t.TempDir()), "", newGeneratorFixture(t), "", nil)
+
+	tests := []struct {
+		code, format string
+		known        bool
+		supported    []models.TemplateOutput
+	}{
+		{"PDP", "html", true, []models.TemplateOutput{{Source: "docx", Format: "docx", ContentType: string(models.FormatDOCX)}, {Source: "docx", Format: "pdf", ContentType: string(models.FormatPDF)}}},
+		{"PDP", "odt", false, []models.TemplateOutput{{Source: "docx", Format: "docx", ContentType: string(models.FormatDOCX)}, {Source: "docx", Format: "pdf", ContentType: string(models.FormatPDF)}}},
+		{"RESTRICTED", "html", true, []models.TemplateOutput{{Source: "html", Format: "pdf", ContentType: string(models.FormatPDF)}}},
+	}
+
+	for _, tt := range tests {
+		_, err := svc.Generate(context.Background(), &models.RequestBody{Code: tt.code, Format: tt.format})
+
+		var ferr *services.UnsupportedFormatError
+		if !errors.As(err, &ferr) {
+			t.Fatalf("%s as %s: error = %v, want UnsupportedFormatError", tt.code, tt.format, err)
+		}
+		if !errors.Is(err, services.ErrUnsupportedFormat) {
+			t.Errorf("%s as %s: error does not wrap ErrUnsupportedFormat", tt.code, tt.format)
+		}
+		if ferr.Known != tt.known {
+			t.Errorf("%s as %s: Known = %v, want %v", tt.code, tt.format, ferr.Known, tt.known)
+		}
+		if !reflect.DeepEqual(ferr.Supported, tt.supported) {
+			t.Errorf("%s as %s: Supported = %v, want %v", tt.code, tt.format, ferr.Supported, tt.supported)
+		}
+	}
+
+	if _, err := svc.Generate(context.Background(), &models.RequestBody{Code: "MISSING", Format: "pdf"}); !errors.Is(err, services.ErrTemplateNotFound) {
+		t.Errorf("missing template: error = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestListTemplates_ReportsOutputs(t *testing.T) {
+	svc := services.NewDocumentService(nil, nil, "", newGeneratorFixture(t), "", nil)
+
+	result, err := svc.ListTemplates(context.Background())
+	if err != nil {
+		t.Fatalf("ListTemplates() error: %v", err)
+	}
+
+	want := map[string][]string{
+		"PDP":        {"docx", "pdf"},
+		"STATEMENT":  {"html", "pdf", "docx"},
+		"RESTRICTED": {"pdf"},
+		"NOTICE":     nil,
+	}
+	for _, tmpl := range result {
+		if expected, ok := want[tmpl.Name]; ok && !reflect.DeepEqual(tmpl.Outputs, expected) {
+			t.Errorf("%s.%s: outputs = %v, want %v", tmpl.Name, tmpl.Format, tmpl.Outputs, expected)
+		}
+	}
+}
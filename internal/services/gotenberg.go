@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+const (
+	gotenbergChromiumHTMLRoute = "/forms/chromium/convert/html"
+	gotenbergLibreOfficeRoute  = "/forms/libreoffice/convert"
+	gotenbergMergeRoute        = "/forms/pdfengines/merge"
+)
+
+type formFile struct {
+	name string
+	data []byte
+}
+
+// postToGotenberg sends files and form fields to one of Gotenberg's form
+// routes and returns the produced PDF.
+func (s *DocumentService) postToGotenberg(ctx context.Context, route string, files []formFile, fields map[string]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	for _, f := range files {
+		part, err := writer.CreateFormFile("files", f.name)
+		if err != nil {
+			return nil, fmt.Errorf("error creating form file: %w", err)
+		}
+		if _, err := part.Write(f.data); err != nil {
+			return nil, fmt.Errorf("error writing to form file: %w", err)
+		}
+	}
+
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("error writing form field %s: %w", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("error closing form file: %w", err)
+	}
+
+	newReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.gotenbergURL+route, buf)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	newReq.Header.Set("Content-Type", writer.FormDataContentType())
+	forwardRequestID(ctx, newReq, gotenbergTraceHeader)
+
+	resp, err := s.gotenberg.Do(newReq)
+	if err != nil {
+		return nil, s.upstreamError(ctx, upstreamGotenberg, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.logger.WarnContext(ctx, "failed to close response body", "err", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, s.upstreamStatusError(ctx, upstreamGotenberg, resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, s.upstreamError(ctx, upstreamGotenberg, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/reqctx"
+	"RBKproject4/internal/webhooks"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrJobNotReady = errors.New("job has not succeeded")
+	// ErrJobInterrupted fails the jobs the service stopped before they
+	// finished, at shutdown or in a crash.
+	ErrJobInterrupted = errors.New("job interrupted")
+)
+
+type jobTask struct {
+	id  string
+	req models.RequestBody
+	// requestID is the ID of the request that submitted the job, so that
+	// the job logs and calls upstreams under it
+	requestID string
+}
+
+type JobOption func(*JobService)
+
+// WithNotifier makes the service POST a signed callback to the request's
+// callbackUrl once a job finishes. resultURL builds the download link that is
+// sent when the document is not inlined.
+func WithNotifier(notifier *webhooks.Notifier, resultURL func(id string) string) JobOption {
+	return func(s *JobService) {
+		s.notifier = notifier
+		s.resultURL = resultURL
+	}
+}
+
+// WithRetention deletes finished jobs and their results once they are older
+// than ttl, checking every interval. Without it jobs are kept forever.
+func WithRetention(ttl, interval time.Duration) JobOption {
+	return func(s *JobService) {
+		if ttl > 0 && interval > 0 {
+			s.retention = ttl
+			s.cleanupInterval = interval
+		}
+	}
+}
+
+// WithCallbackPolicy sets the hosts callbacks may be sent to. By default any
+// host with a public address is allowed.
+func WithCallbackPolicy(policy webhooks.HostPolicy) JobOption {
+	return func(s *JobService) {
+		s.callbackPolicy = policy
+	}
+}
+
+// JobService runs document generation in the background on a fixed number of
+// workers, keeping job state in a jobs.Store.
+type JobService struct {
+	docs   *DocumentService
+	store  jobs.Store
+	logger *slog.Logger
+	queue  chan jobTask
+
+	notifier       *webhooks.Notifier
+	resultURL      func(id string) string
+	callbackPolicy webhooks.HostPolicy
+
+	retention       time.Duration
+	cleanupInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// deliveries outlives ctx, so that callbacks of jobs finished during
+	// shutdown still go out; Close cancels it once its deadline is up
+	deliveries       context.Context
+	cancelDeliveries context.CancelFunc
+
+	wg        sync.WaitGroup
+	callbacks sync.WaitGroup
+	mu        sync.RWMutex
+	closed    bool
+
+	// deliveryMu serialises the read-modify-write of a job's delivery history
+	deliveryMu sync.Mutex
+}
+
+func NewJobService(logger *slog.Logger, docs *DocumentService, store jobs.Store, workers, queueSize int, opts ...JobOption) *JobService {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deliveries, cancelDeliveries := context.WithCancel(context.Background())
+	s := &JobService{
+		docs:             docs,
+		store:            store,
+		logger:           logger,
+		queue:            make(chan jobTask, queueSize),
+		ctx:              ctx,
+		cancel:           cancel,
+		deliveries:       deliveries,
+		cancelDeliveries: cancelDeliveries,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.recoverJobs()
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	if s.retention > 0 {
+		s.wg.Add(1)
+		go s.cleanup()
+	}
+	return s
+}
+
+// recoverJobs fails the jobs a previous run left queued or running, as their
+// tasks went with it.
+func (s *JobService) recoverJobs() {
+	list, err := s.store.List(s.ctx)
+	if err != nil {
+		s.logger.Error("failed to list jobs", "error", err)
+		return
+	}
+	for _, job := range list {
+		if job.Finished() {
+			continue
+		}
+		ctx := reqctx.WithClientID(s.ctx, job.ClientID)
+		s.fail(ctx, job, fmt.Errorf("%w: service restarted before the job finished", ErrJobInterrupted))
+	}
+}
+
+// cleanup deletes expired jobs until the service is closed.
+func (s *JobService) cleanup() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.expire(time.Now().Add(-s.retention))
+		}
+	}
+}
+
+// expire deletes the jobs that finished before cutoff.
+func (s *JobService) expire(cutoff time.Time) {
+	list, err := s.store.List(s.ctx)
+	if err != nil {
+		s.logger.Error("failed to list jobs", "error", err)
+		return
+	}
+	for _, job := range list {
+		if job.FinishedAt == nil || job.FinishedAt.After(cutoff) {
+			continue
+		}
+		if err := s.store.Delete(s.ctx, job.ID); err != nil {
+			s.logger.Error("failed to delete expired job", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *JobService) Submit(ctx context.Context, req *models.RequestBody) (*models.Job, error) {
+	// a bad format or bad data is reported to the caller right away instead
+	// of as a failed job
+	if !s.docs.KnowsFormat(req.Format) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, req.Format)
+	}
+	if err := s.docs.authorize(ctx, req.Code, req.Format); err != nil {
+		return nil, err
+	}
+	if err := s.docs.Validate(ctx, req); err != nil {
+		return nil, err
+	}
+	if req.CallbackURL != "" {
+		if err := s.callbackPolicy.Check(req.CallbackURL); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidData, err)
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, fmt.Errorf("error generating job id: %w", err)
+	}
+
+	job := &models.Job{
+		ID:        id,
+		Status:    models.JobQueued,
+		Code:      req.Code,
+		Format:    req.Format,
+		ClientID:  reqctx.ClientID(ctx),
+		CreatedAt: time.Now().UTC(),
+
+		CallbackURL: req.CallbackURL,
+	}
+	if err := s.store.Save(ctx, job); err != nil {
+		return nil, fmt.Errorf("error saving job: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queued := false
+	if !s.closed {
+		select {
+		case s.queue <- jobTask{id: id, req: *req, requestID: reqctx.RequestID(ctx)}:
+			queued = true
+		default:
+		}
+	}
+
+	if !queued {
+		// the caller is told right away, so no callback is sent
+		s.markFailed(ctx, job, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+	return job, nil
+}
+
+// Get returns a job of the client in ctx. Other clients' jobs are reported as
+// not found, so that their IDs can't be probed.
+func (s *JobService) Get(ctx context.Context, id string) (*models.Job, error) {
+	job, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.docs.mayAccessJob(ctx, job.ClientID) {
+		return nil, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+// Result returns the finished document of a job that has succeeded.
+func (s *JobService) Result(ctx context.Context, id string) (*models.Document, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobSucceeded || job.Result == nil {
+		return nil, ErrJobNotReady
+	}
+
+	data, err := s.store.Result(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Document{
+		Data:     data,
+		Format:   job.Result.Format,
+		Filename: job.Result.Filename,
+	}, nil
+}
+
+func (s *JobService) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case task, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			s.run(task)
+		}
+	}
+}
+
+func (s *JobService) run(task jobTask) {
+	ctx := reqctx.WithRequestID(s.ctx, task.requestID)
+
+	job, err := s.store.Get(ctx, task.id)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to load job", "job_id", task.id, "error", err)
+		return
+	}
+	ctx = reqctx.WithClientID(ctx, job.ClientID)
+
+	started := time.Now().UTC()
+	job.Status = models.JobRunning
+	job.StartedAt = &started
+	if err := s.store.Save(ctx, job); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save job", "job_id", job.ID, "error", err)
+	}
+
+	doc, err := s.docs.Generate(ctx, &task.req)
+	if err != nil {
+		s.fail(ctx, job, err)
+		return
+	}
+
+	if err := s.store.SaveResult(ctx, job.ID, doc.Data); err != nil {
+		s.fail(ctx, job, fmt.Errorf("error saving result: %w", err))
+		return
+	}
+
+	sum := sha256.Sum256(doc.Data)
+	job.Status = models.JobSucceeded
+	job.Result = &models.JobResult{
+		Filename: doc.Filename,
+		Format:   doc.Format,
+		Size:     len(doc.Data),
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+	s.finish(ctx, job)
+
+	if task.req.CallbackInline {
+		s.notify(job, doc.Data)
+	} else {
+		s.notify(job, nil)
+	}
+}
+
+func (s *JobService) fail(ctx context.Context, job *models.Job, err error) {
+	s.markFailed(ctx, job, err)
+	s.notify(job, nil)
+}
+
+func (s *JobService) markFailed(ctx context.Context, job *models.Job, err error) {
+	s.logger.WarnContext(ctx, "job failed", "job_id", job.ID, "code", job.Code, "error", err)
+	job.Status = models.JobFailed
+	// job.Error is served to the client and its webhook
+	job.Error = ErrorDetail(err)
+	s.finish(ctx, job)
+}
+
+// notify delivers the completion callback in the background so that retries
+// never hold up a worker. inline, when set, is sent base64-encoded instead of
+// a download link.
+func (s *JobService) notify(job *models.Job, inline []byte) {
+	if s.notifier == nil || job.CallbackURL == "" {
+		return
+	}
+
+	payload := &models.WebhookPayload{
+		JobID:  job.ID,
+		Status: job.Status,
+		Code:   job.Code,
+		Format: job.Format,
+		Error:  job.Error,
+	}
+	if job.Result != nil {
+		payload.Checksum = job.Result.Checksum
+		payload.Filename = job.Result.Filename
+		if inline != nil {
+			payload.Content = base64.StdEncoding.EncodeToString(inline)
+		} else if s.resultURL != nil {
+			payload.DownloadURL = s.resultURL(job.ID)
+		}
+	}
+
+	s.callbacks.Add(1)
+	go func() {
+		defer s.callbacks.Done()
+		err := s.notifier.Deliver(s.deliveries, job.CallbackURL, job.ClientID, payload, func(d models.WebhookDelivery) {
+			s.updateNotification(job.ID, func(j *models.Job) { j.Deliveries = append(j.Deliveries, d) })
+		})
+		// a delivery cut short by shutdown has not failed
+		if s.deliveries.Err() != nil {
+			return
+		}
+		status := models.NotificationDelivered
+		if err != nil {
+			status = models.NotificationFailed
+		}
+		s.updateNotification(job.ID, func(j *models.Job) { j.Notification = status })
+	}()
+}
+
+// updateNotification applies update to the stored job id, one callback
+// outcome at a time.
+func (s *JobService) updateNotification(id string, update func(*models.Job)) {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	ctx := context.Background()
+	job, err := s.store.Get(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to load job", "job_id", id, "error", err)
+		return
+	}
+	update(job)
+	if err := s.store.Save(ctx, job); err != nil {
+		s.logger.Error("failed to save job", "job_id", id, "error", err)
+	}
+}
+
+func (s *JobService) finish(ctx context.Context, job *models.Job) {
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if job.StartedAt != nil {
+		job.DurationMs = finished.Sub(*job.StartedAt).Milliseconds()
+	}
+
+	// the service context may already be cancelled during shutdown, but the
+	// final state still has to reach the store
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	if err := s.store.Save(ctx, job); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save job", "job_id", job.ID, "error", err)
+	}
+}
+
+// Close stops accepting jobs, cancels the ones in progress and marks whatever
+// is still queued as failed. It then waits for the outstanding callbacks until
+// ctx is done.
+func (s *JobService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	// callbacks still out when ctx is done are given up
+	defer s.cancelDeliveries()
+
+	if err := waitFor(ctx, &s.wg); err != nil {
+		return err
+	}
+
+	for drained := false; !drained; {
+		select {
+		case task := <-s.queue:
+			job, err := s.store.Get(ctx, task.id)
+			if err != nil {
+				continue
+			}
+			s.fail(ctx, job, fmt.Errorf("%w: service shut down before the job started", ErrJobInterrupted))
+		default:
+			drained = true
+		}
+	}
+
+	// including the callbacks of the jobs failed above
+	return waitFor(ctx, &s.callbacks)
+}
+
+// waitFor waits for wg until ctx is done.
+func waitFor(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
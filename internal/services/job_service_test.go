@@ -0,0 +1,346 @@
+package services_test
+
+import (
+	"RBKproject4/internal/jobs"
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"RBKproject4/internal/webhooks"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitForJob(t *testing.T, svc *services.JobService, id string) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return nil
+}
+
+func TestJobService_GeneratesDocument(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello {{ name }}!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 2, 10)
+	defer svc.Close(context.Background())
+
+	job, err := svc.Submit(context.Background(), &models.RequestBody{
+		Code:   "greet",
+		Format: "html",
+		Data:   map[string]any{"name": "World"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	if job.Status != models.JobQueued {
+		t.Errorf("status = %s, want %s", job.Status, models.JobQueued)
+	}
+
+	job = waitForJob(t, svc, job.ID)
+	if job.Status != models.JobSucceeded {
+		t.Fatalf("status = %s (%s), want %s", job.Status, job.Error, models.JobSucceeded)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Error("expected start and finish timings to be set")
+	}
+
+	doc, err := svc.Result(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Result() error: %v", err)
+	}
+	if string(doc.Data) != "Hello World!" {
+		t.Errorf("Result() = %q, want %q", doc.Data, "Hello World!")
+	}
+}
+
+func TestJobService_RecordsFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(t.TempDir()), "", t.TempDir(), "", nil)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 10)
+	defer svc.Close(context.Background())
+
+	job, err := svc.Submit(context.Background(), &models.RequestBody{Code: "missing", Format: "html"})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+
+	job = waitForJob(t, svc, job.ID)
+	if job.Status != models.JobFailed || job.Error == "" {
+		t.Errorf("job = %+v, want failed with an error", job)
+	}
+
+	if _, err := svc.Result(context.Background(), job.ID); !errors.Is(err, services.ErrJobNotReady) {
+		t.Errorf("Result() error = %v, want ErrJobNotReady", err)
+	}
+}
+
+func TestJobService_KeepsRenderErrorsOutOfJob(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "broken.html"), []byte(`{% include "missing_part.html" %}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 10)
+	defer svc.Close(context.Background())
+
+	job, err := svc.Submit(context.Background(), &models.RequestBody{Code: "broken", Format: "html"})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+
+	job = waitForJob(t, svc, job.ID)
+	if job.Status != models.JobFailed || job.Error == "" {
+		t.Fatalf("job = %+v, want failed with an error", job)
+	}
+	if strings.Contains(job.Error, tmpDir) || strings.Contains(job.Error, "missing_part") {
+		t.Errorf("job error %q leaks the render error", job.Error)
+	}
+}
+
+// callbackReceiver counts the callbacks it is sent, by job status.
+type callbackReceiver struct {
+	mu       sync.Mutex
+	statuses []models.JobStatus
+}
+
+func (c *callbackReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload models.WebhookPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.statuses = append(c.statuses, payload.Status)
+	c.mu.Unlock()
+}
+
+func (c *callbackReceiver) received() []models.JobStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.JobStatus(nil), c.statuses...)
+}
+
+func newNotifyingJobService(t *testing.T, docs *services.DocumentService, workers, queueSize int) *services.JobService {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notifier := webhooks.NewNotifier(logger, http.DefaultClient, map[string]string{"": "secret"}, 1, time.Millisecond)
+	return services.NewJobService(logger, docs, jobs.NewMemoryStore(), workers, queueSize,
+		services.WithNotifier(notifier, nil),
+		services.WithCallbackPolicy(webhooks.HostPolicy{Private: true}))
+}
+
+func TestJobService_NoCallbackForRejectedJob(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	receiver := &callbackReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	docs := services.NewDocumentService(slog.New(slog.NewTextHandler(io.Discard, nil)), renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	svc := newNotifyingJobService(t, docs, 1, 1)
+	if err := svc.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.Submit(context.Background(), &models.RequestBody{Code: "greet", Format: "html", CallbackURL: srv.URL})
+	if !errors.Is(err, services.ErrQueueFull) {
+		t.Fatalf("Submit() error = %v, want ErrQueueFull", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := receiver.received(); len(got) != 0 {
+		t.Errorf("callbacks sent for a rejected job: %v", got)
+	}
+}
+
+func TestJobService_CloseDeliversPendingCallbacks(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "slow.docx"), []byte("template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the Python service holds the running job until shutdown cancels it
+	started := make(chan struct{}, 1)
+	python := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices the client going away once the body is read
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer python.Close()
+	receiver := &callbackReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), python.URL, tmpDir, "", python.Client())
+	svc := newNotifyingJobService(t, docs, 1, 1)
+
+	req := &models.RequestBody{Code: "slow", Format: "docx", CallbackURL: srv.URL}
+	if _, err := svc.Submit(context.Background(), req); err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	<-started
+	// stays queued behind the running one
+	if _, err := svc.Submit(context.Background(), req); err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := svc.Close(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	got := receiver.received()
+	if len(got) != 2 || got[0] != models.JobFailed || got[1] != models.JobFailed {
+		t.Errorf("callbacks = %v, want two failed jobs", got)
+	}
+}
+
+func TestJobService_RejectsPrivateCallbackHost(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 1)
+	defer svc.Close(context.Background())
+
+	_, err := svc.Submit(context.Background(), &models.RequestBody{Code: "greet", Format: "html", CallbackURL: "http://169.254.169.254/latest"})
+	if !errors.Is(err, services.ErrInvalidData) || !errors.Is(err, webhooks.ErrHostNotAllowed) {
+		t.Errorf("Submit() error = %v, want ErrInvalidData for the host", err)
+	}
+}
+
+func TestJobService_FailsJobsLeftByPreviousRun(t *testing.T) {
+	store, err := jobs.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, job := range []*models.Job{
+		{ID: "queued", Status: models.JobQueued},
+		{ID: "running", Status: models.JobRunning},
+		{ID: "done", Status: models.JobSucceeded},
+	} {
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, nil, "", t.TempDir(), "", nil)
+	svc := services.NewJobService(logger, docs, store, 1, 1)
+	defer svc.Close(ctx)
+
+	for id, want := range map[string]models.JobStatus{"queued": models.JobFailed, "running": models.JobFailed, "done": models.JobSucceeded} {
+		job, err := svc.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get(%s) error: %v", id, err)
+		}
+		if job.Status != want {
+			t.Errorf("job %s status = %s, want %s", id, job.Status, want)
+		}
+		if want == models.JobFailed && !strings.HasPrefix(job.Error, services.ErrJobInterrupted.Error()) {
+			t.Errorf("job %s error = %q", id, job.Error)
+		}
+	}
+}
+
+func TestJobService_DeletesExpiredJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 10,
+		services.WithRetention(200*time.Millisecond, 10*time.Millisecond))
+	defer svc.Close(context.Background())
+
+	job, err := svc.Submit(context.Background(), &models.RequestBody{Code: "greet", Format: "html"})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	waitForJob(t, svc, job.ID)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := svc.Get(context.Background(), job.ID); errors.Is(err, jobs.ErrNotFound) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("job not deleted after its retention")
+}
+
+func TestJobService_MarksRefusedCallbackFailed(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "greet.html"), []byte("Hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	receiver := &callbackReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	docs := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	// the URL passes at submit time, but the address it connects to doesn't,
+	// as when a host name is rebound to a private address
+	notifier := webhooks.NewNotifier(logger, webhooks.HostPolicy{}.Client(time.Second), map[string]string{"": "secret"}, 5, time.Millisecond)
+	svc := services.NewJobService(logger, docs, jobs.NewMemoryStore(), 1, 1,
+		services.WithNotifier(notifier, nil),
+		services.WithCallbackPolicy(webhooks.HostPolicy{Private: true}))
+	defer svc.Close(context.Background())
+
+	job, err := svc.Submit(context.Background(), &models.RequestBody{Code: "greet", Format: "html", CallbackURL: srv.URL})
+	if err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Notification == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = svc.Get(context.Background(), job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Notification != models.NotificationFailed || len(job.Deliveries) != 1 {
+		t.Errorf("notification = %q after %d deliveries, want failed after 1", job.Notification, len(job.Deliveries))
+	}
+	if got := receiver.received(); len(got) != 0 {
+		t.Errorf("callbacks reached a refused host: %v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const manifestSuffix = ".manifest.yaml"
+
+var (
+	validCode           = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	validHeaderFooter   = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.(header|footer))?$`)
+	unsafeFilenameChars = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]+`)
+)
+
+// loadManifest reads the manifest of code. A template without one gets an
+// empty manifest.
+func (s *DocumentService) loadManifest(code string) (*models.TemplateManifest, error) {
+	// code comes from the request and is joined into a path
+	if !validCode.MatchString(code) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, code)
+	}
+	data, err := os.ReadFile(filepath.Join(s.templateDir, code+manifestSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		return &models.TemplateManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	var manifest models.TemplateManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing manifest of %s: %w", code, err)
+	}
+	return &manifest, nil
+}
+
+// templateSources lists the template files present for code, e.g. html, docx.
+func (s *DocumentService) templateSources(code string) []string {
+	var sources []string
+	for _, ext := range s.sources {
+		if s.templateExists(code, ext) {
+			sources = append(sources, ext)
+		}
+	}
+	return sources
+}
+
+// GetTemplate returns everything known about code: its template files and its
+// full manifest, schema included.
+func (s *DocumentService) GetTemplate(ctx context.Context, code string) (_ *models.TemplateDetails, err error) {
+	_, span := startSpan(ctx, "DocumentService.GetTemplate", attrTemplateCode.String(code))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.authorize(ctx, code, ""); err != nil {
+		return nil, err
+	}
+	if !validCode.MatchString(code) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, code)
+	}
+
+	sources := s.templateSources(code)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, code)
+	}
+
+	manifest, err := s.loadManifest(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TemplateDetails{
+		Code:             code,
+		Sources:          sources,
+		Outputs:          s.outputs(code, manifest),
+		TemplateManifest: *manifest,
+	}, nil
+}
+
+// documentFilename renders the manifest filename pattern with the request
+// data and appends ext, falling back to fallback when there is no pattern or
+// it renders to nothing usable.
+func (s *DocumentService) documentFilename(ctx context.Context, manifest *models.TemplateManifest, ext, fallback string, dataMap map[string]interface{}) string {
+	if manifest == nil || manifest.Filename == "" {
+		return fallback
+	}
+
+	name, err := renderers.RenderString(manifest.Filename, dataMap)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to render filename pattern", "pattern", manifest.Filename, "error", err)
+		return fallback
+	}
+
+	name = strings.TrimSpace(unsafeFilenameChars.ReplaceAllString(name, "_"))
+	if name == "" {
+		return fallback
+	}
+	return name + "." + ext
+}
@@ -0,0 +1,129 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const statementManifest = `
+title:
+  ru: Выписка по лицевому счету
+  kk: Жеке шот бойынша үзінді көшірме
+  en: Account statement
+owner: retail-accounts
+formats: [html, pdf]
+filename: "statement_{{ accountNumber }}_{{ period }}"
+schema:
+  type: object
+  required: [accountNumber]
+  properties:
+    accountNumber:
+      type: string
+`
+
+func newManifestFixture(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "ACCOUNT_STATEMENT.html"), "<p>{{ accountNumber }}</p>")
+	writeFile(t, filepath.Join(tmpDir, "ACCOUNT_STATEMENT.manifest.yaml"), statementManifest)
+	writeFile(t, filepath.Join(tmpDir, "PDP.docx"), "docx")
+	return tmpDir
+}
+
+func TestListTemplates_IncludesManifestMetadata(t *testing.T) {
+	tmpDir := newManifestFixture(t)
+	svc := services.NewDocumentService(nil, nil, "", tmpDir, "", nil)
+
+	result, err := svc.ListTemplates(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byName := map[string]*models.Template{}
+	for _, tmpl := range result {
+		byName[tmpl.Name] = tmpl
+	}
+
+	statement := byName["ACCOUNT_STATEMENT"]
+	if statement == nil {
+		t.Fatal("ACCOUNT_STATEMENT missing from list")
+	}
+	if statement.Title["kk"] != "Жеке шот бойынша үзінді көшірме" || statement.Owner != "retail-accounts" {
+		t.Errorf("metadata not loaded: %+v", statement)
+	}
+	if len(statement.Formats) != 2 {
+		t.Errorf("formats = %v, want [html pdf]", statement.Formats)
+	}
+
+	if pdp := byName["PDP"]; pdp == nil || pdp.Owner != "" || pdp.Title != nil {
+		t.Errorf("template without manifest = %+v", pdp)
+	}
+}
+
+func TestGetTemplate(t *testing.T) {
+	tmpDir := newManifestFixture(t)
+	svc := services.NewDocumentService(nil, nil, "", tmpDir, "", nil)
+
+	details, err := svc.GetTemplate(context.Background(), "ACCOUNT_STATEMENT")
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %v", err)
+	}
+	if details.Code != "ACCOUNT_STATEMENT" || len(details.Sources) != 1 || details.Sources[0] != "html" {
+		t.Errorf("details = %+v", details)
+	}
+	if details.Schema["type"] != "object" {
+		t.Errorf("schema not returned: %v", details.Schema)
+	}
+
+	for _, code := range []string{"MISSING", "../ACCOUNT_STATEMENT"} {
+		if _, err := svc.GetTemplate(context.Background(), code); !errors.Is(err, services.ErrTemplateNotFound) {
+			t.Errorf("GetTemplate(%q) error = %v, want ErrTemplateNotFound", code, err)
+		}
+	}
+}
+
+func TestGenerate_UsesManifestFilenameAndFormats(t *testing.T) {
+	tmpDir := newManifestFixture(t)
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	doc, err := svc.GenerateHTML(context.Background(), &models.RequestBody{
+		Code:   "ACCOUNT_STATEMENT",
+		Format: "html",
+		Data:   map[string]any{"accountNumber": "KZ12", "period": "2024/01"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateHTML() error: %v", err)
+	}
+	if doc.Filename != "statement_KZ12_2024_01.html" {
+		t.Errorf("filename = %q", doc.Filename)
+	}
+
+	_, err = svc.Generate(context.Background(), &models.RequestBody{Code: "ACCOUNT_STATEMENT", Format: "docx"})
+	if !errors.Is(err, services.ErrUnsupportedFormat) {
+		t.Errorf("Generate(docx) error = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestLoadManifest_RejectsPathsInCode(t *testing.T) {
+	root := t.TempDir()
+	tmpDir := filepath.Join(root, "templates")
+	writeFile(t, filepath.Join(tmpDir, "PDP.html"), "<p></p>")
+	// a manifest outside the template dir whose schema would reject the data
+	writeFile(t, filepath.Join(root, "outside.manifest.yaml"), "schema:\n  type: string\n")
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+	for _, code := range []string{"../outside", "sub/../../outside"} {
+		req := &models.RequestBody{Code: code, Format: "html", Data: map[string]any{}}
+		if _, err := svc.Generate(context.Background(), req); !errors.Is(err, services.ErrTemplateNotFound) {
+			t.Errorf("Generate(%q) error = %v, want ErrTemplateNotFound", code, err)
+		}
+		if err := svc.Validate(context.Background(), req); !errors.Is(err, services.ErrTemplateNotFound) {
+			t.Errorf("Validate(%q) error = %v, want ErrTemplateNotFound", code, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratePDF_AppliesManifestAndRequestOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.html"), "<p>{{ clientName }}</p>")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.footer.html"), "<p>footer {{ clientName }}</p>")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.manifest.yaml"), `
+pdf:
+  paperSize: A4
+  landscape: true
+  marginTop: 20mm
+`)
+
+	var fields map[string]string
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fields = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		for _, fh := range r.MultipartForm.File["files"] {
+			f, _ := fh.Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			files[fh.Filename] = string(data)
+		}
+		w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, srv.URL, srv.Client())
+
+	portrait := false
+	_, err := svc.GeneratePDF(context.Background(), &models.RequestBody{
+		Code:   "STATEMENT",
+		Format: "pdf",
+		Data:   map[string]any{"clientName": "ТОО Ромашка"},
+		PDF:    &models.PDFOptions{Landscape: &portrait, PageRanges: "1-3"},
+	})
+	if err != nil {
+		t.Fatalf("GeneratePDF() error: %v", err)
+	}
+
+	want := map[string]string{
+		"paperWidth":       "210mm",
+		"paperHeight":      "297mm",
+		"landscape":        "false",
+		"marginTop":        "20mm",
+		"nativePageRanges": "1-3",
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("field %s = %q, want %q", k, fields[k], v)
+		}
+	}
+
+	if files["footer.html"] != "<p>footer ТОО Ромашка</p>" {
+		t.Errorf("footer.html = %q", files["footer.html"])
+	}
+	if _, ok := files["header.html"]; ok {
+		t.Error("header.html sent although the template has none")
+	}
+}
+
+func TestGeneratePDF_RejectsInvalidOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.html"), "<p></p>")
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	scale := 3.0
+	tests := []struct {
+		name string
+		opts *models.PDFOptions
+	}{
+		{"unknown paper size", &models.PDFOptions{PaperSize: "B7"}},
+		{"scale out of range", &models.PDFOptions{Scale: &scale}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GeneratePDF(context.Background(), &models.RequestBody{
+				Code:   "STATEMENT",
+				Format: "pdf",
+				PDF:    tt.opts,
+			})
+			if !errors.Is(err, services.ErrInvalidData) {
+				t.Fatalf("error = %v, want ErrInvalidData", err)
+			}
+		})
+	}
+}
+
+func TestListTemplates_SkipsTemplateCompanions(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"STATEMENT.html", "STATEMENT.header.html"} {
+		writeFile(t, filepath.Join(tmpDir, name), "x")
+	}
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.manifest.yaml"), "owner: cards\n")
+
+	svc := services.NewDocumentService(nil, nil, "", tmpDir, "", nil)
+	result, err := svc.ListTemplates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Name != "STATEMENT" {
+		t.Errorf("ListTemplates() = %+v, want only STATEMENT", result)
+	}
+}
+
+func TestGeneratePDF_RejectsUndeclaredHeaderFooter(t *testing.T) {
+	root := t.TempDir()
+	tmpDir := filepath.Join(root, "templates")
+	writeFile(t, filepath.Join(root, "secret.html"), "<p>secret</p>")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.html"), "<p></p>")
+	writeFile(t, filepath.Join(tmpDir, "OTHER.header.html"), "<p>other</p>")
+	writeFile(t, filepath.Join(tmpDir, "SHARED.footer.html"), "<p>shared</p>")
+	writeFile(t, filepath.Join(tmpDir, "STATEMENT.manifest.yaml"), "pdf:\n  footer: SHARED.footer\n")
+
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, fh := range r.MultipartForm.File["files"] {
+			f, _ := fh.Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			files[fh.Filename] = string(data)
+		}
+		w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, srv.URL, srv.Client())
+
+	for _, header := range []string{"../secret", "../templates/STATEMENT", "OTHER.header"} {
+		_, err := svc.GeneratePDF(context.Background(), &models.RequestBody{
+			Code:   "STATEMENT",
+			Format: "pdf",
+			PDF:    &models.PDFOptions{Header: header},
+		})
+		if !errors.Is(err, services.ErrInvalidData) {
+			t.Errorf("header %q: error = %v, want ErrInvalidData", header, err)
+		}
+	}
+	if len(files) != 0 {
+		t.Fatalf("files sent for a rejected header: %v", files)
+	}
+
+	// the footer the manifest declares is still used
+	if _, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: "STATEMENT", Format: "pdf"}); err != nil {
+		t.Fatalf("GeneratePDF() error: %v", err)
+	}
+	if files["footer.html"] != "<p>shared</p>" {
+		t.Errorf("footer.html = %q, want the declared footer", files["footer.html"])
+	}
+}
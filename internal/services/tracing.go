@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "RBKproject4/internal/services"
+
+// Span attributes of generation.
+const (
+	attrTemplateCode = attribute.Key("template.code")
+	attrFormat       = attribute.Key("document.format")
+	attrTemplateName = attribute.Key("template.name")
+)
+
+// startSpan starts a span named name as a child of the span in ctx. The
+// tracer is looked up every time so that a provider installed later is used.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marked as failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// installTracer records spans in memory for the rest of the test.
+func installTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func TestGeneratePDF_Traced(t *testing.T) {
+	exporter := installTracer(t)
+
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{"HTML_ONLY.html": "<p>{{ name }}</p>", "DOCX_ONLY.docx": "template"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	traceparents := map[string]string{}
+	fake := &upstreams{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents[r.URL.Path] = r.Header.Get("traceparent")
+		mu.Unlock()
+		fake.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, srv.Client())
+
+	// the trace of the incoming request, as taken from its traceparent
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+	tests := []struct {
+		code      string
+		wantSpans []string
+		wantPaths []string
+	}{
+		{"HTML_ONLY", []string{"DocumentService.Generate", "pongo2.Render", "gotenberg POST /forms/chromium/convert/html"},
+			[]string{"/forms/chromium/convert/html"}},
+		{"DOCX_ONLY", []string{"DocumentService.Generate", "python service POST /docx/render", "gotenberg POST /forms/libreoffice/convert"},
+			[]string{"/docx/render", "/forms/libreoffice/convert"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			exporter.Reset()
+			if _, err := svc.GeneratePDF(ctx, &models.RequestBody{Code: tt.code, Format: "pdf", Data: map[string]any{"name": "Aigerim"}}); err != nil {
+				t.Fatalf("GeneratePDF() error: %v", err)
+			}
+
+			spans := map[string]tracetest.SpanStub{}
+			for _, s := range exporter.GetSpans() {
+				spans[s.Name] = s
+			}
+			for _, name := range tt.wantSpans {
+				s, ok := spans[name]
+				if !ok {
+					t.Errorf("no span %q, got %v", name, spanNames(exporter.GetSpans()))
+					continue
+				}
+				if s.SpanContext.TraceID() != parent.TraceID() {
+					t.Errorf("span %q in trace %s, want %s", name, s.SpanContext.TraceID(), parent.TraceID())
+				}
+			}
+			if root := spans["DocumentService.Generate"]; root.Parent.SpanID() != parent.SpanID() {
+				t.Errorf("DocumentService.Generate parent = %s, want %s", root.Parent.SpanID(), parent.SpanID())
+			}
+
+			for _, path := range tt.wantPaths {
+				mu.Lock()
+				tp := traceparents[path]
+				mu.Unlock()
+				if !strings.Contains(tp, parent.TraceID().String()) {
+					t.Errorf("traceparent sent to %s = %q, want trace %s", path, tp, parent.TraceID())
+				}
+			}
+		})
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	return names
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"RBKproject4/internal/reqctx"
+	"RBKproject4/internal/upstream"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// upstreamBodyLimit caps how much of an upstream's error response is logged.
+const upstreamBodyLimit = 4 << 10
+
+// Names of the upstream services in errors and logs.
+const (
+	upstreamPython    = "python service"
+	upstreamGotenberg = "gotenberg"
+)
+
+// Headers the request ID is forwarded in, so that the upstreams' logs can be
+// matched with ours. Gotenberg logs its own trace header as "trace".
+const (
+	requestIDHeader      = "X-Request-ID"
+	gotenbergTraceHeader = "Gotenberg-Trace"
+)
+
+// forwardRequestID sets header on req to the ID of the request being served,
+// if there is one.
+func forwardRequestID(ctx context.Context, req *http.Request, header string) {
+	if id := reqctx.RequestID(ctx); id != "" {
+		req.Header.Set(header, id)
+	}
+}
+
+// upstreamError classifies a request to an upstream that got no response.
+// err names the upstream's URL, so it goes to the log and not into the
+// returned error, which clients see.
+func (s *DocumentService) upstreamError(ctx context.Context, service string, err error) error {
+	if ctx.Err() == context.Canceled {
+		return ctx.Err()
+	}
+	var oerr *upstream.OpenError
+	if errors.As(err, &oerr) {
+		return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	s.logger.ErrorContext(ctx, "upstream request failed", "service", service, "error", err)
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %s did not answer in time", ErrUpstreamTimeout, service)
+	}
+	return fmt.Errorf("%w: %s is unreachable", ErrUpstreamUnavailable, service)
+}
+
+// upstreamStatusError classifies an error response of an upstream. Its body
+// is logged, as it can echo the request data.
+func (s *DocumentService) upstreamStatusError(ctx context.Context, service string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, upstreamBodyLimit))
+	s.logger.ErrorContext(ctx, "upstream returned an error", "service", service, "status", resp.StatusCode, "body", string(body))
+
+	switch {
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		// the Python service couldn't render the template with this data;
+		// its message is in the log above
+		return fmt.Errorf("%w: %s could not render the template with this data", ErrInvalidData, service)
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return fmt.Errorf("%w: %s timed out", ErrUpstreamTimeout, service)
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("%w: %s returned status %d", ErrUpstreamUnavailable, service, resp.StatusCode)
+	default:
+		// the upstream understood the request but couldn't render it
+		return fmt.Errorf("%w: %s rejected the request with status %d", ErrRenderFailed, service, resp.StatusCode)
+	}
+}
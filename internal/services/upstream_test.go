@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratePDF_UpstreamErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "STATEMENT.docx"), []byte("template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const secret = "IIN 900101300123"
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		closed  bool
+		want    error
+	}{
+		{"rejected", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad template for "+secret, http.StatusBadRequest)
+		}, false, services.ErrRenderFailed},
+		{"unrenderable", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"detail": "docx render failed: 'dict object' has no attribute '` + secret + `'"}`))
+		}, false, services.ErrInvalidData},
+		{"failing", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, secret, http.StatusServiceUnavailable)
+		}, false, services.ErrUpstreamUnavailable},
+		{"gateway timeout", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}, false, services.ErrUpstreamTimeout},
+		{"slow", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}, false, services.ErrUpstreamTimeout},
+		{"down", nil, true, services.ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			if tt.closed {
+				srv.Close()
+			}
+
+			logs := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(logs, nil))
+			client := &http.Client{Timeout: 50 * time.Millisecond}
+			svc := services.NewDocumentService(logger, renderers.NewPongo2Renderer(tmpDir), srv.URL, tmpDir, srv.URL, client)
+
+			_, err := svc.GeneratePDF(context.Background(), &models.RequestBody{Code: "STATEMENT", Format: "pdf"})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if strings.Contains(err.Error(), srv.URL) || strings.Contains(err.Error(), secret) {
+				t.Errorf("error leaks the upstream URL or response: %v", err)
+			}
+			if (tt.name == "rejected" || tt.name == "unrenderable") && !strings.Contains(logs.String(), secret) {
+				t.Errorf("upstream response not logged: %s", logs)
+			}
+		})
+	}
+}
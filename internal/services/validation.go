@@ -0,0 +1,173 @@
+package services
+
+import (
+	"RBKproject4/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var ErrInvalidData = errors.New("invalid data")
+
+// ValidationError lists every violation of a template's schema found in the
+// request data.
+type ValidationError struct {
+	Code       string
+	Violations []models.Violation
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("data does not match the schema of %s: %d violation(s)", e.Code, len(e.Violations))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidData
+}
+
+// Validate checks req.Data against the JSON Schema in the manifest of
+// req.Code. Templates without a schema accept any data. With req.Strict set,
+// properties the schema does not declare are rejected too.
+func (s *DocumentService) Validate(ctx context.Context, req *models.RequestBody) (err error) {
+	_, span := startSpan(ctx, "DocumentService.Validate", attrTemplateCode.String(req.Code))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.authorize(ctx, req.Code, ""); err != nil {
+		return err
+	}
+	manifest, err := s.loadManifest(req.Code)
+	if err != nil {
+		return err
+	}
+	return validateData(req.Code, manifest.Schema, req.Data, req.Strict)
+}
+
+func validateData(code string, schema map[string]any, data any, strict bool) error {
+	if schema == nil {
+		return nil
+	}
+
+	compiled, err := compileSchema(code, schema, strict)
+	if err != nil {
+		return err
+	}
+
+	// round-trip through JSON so structs and typed maps are validated the way
+	// the renderers will see them
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error converting data to json: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("error converting data to json: %w", err)
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return fmt.Errorf("error validating data: %w", err)
+	}
+
+	violations := collectViolations(verr, message.NewPrinter(language.English), nil)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return &ValidationError{Code: code, Violations: violations}
+}
+
+func compileSchema(code string, schema map[string]any, strict bool) (*jsonschema.Schema, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("error converting schema of %s: %w", code, err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error converting schema of %s: %w", code, err)
+	}
+	if strict {
+		closeObjects(doc)
+	}
+
+	url := "mem:///" + code + ".schema.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("error loading schema of %s: %w", code, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling schema of %s: %w", code, err)
+	}
+	return compiled, nil
+}
+
+// closeObjects forbids undeclared properties on every object schema that
+// declares properties and does not say otherwise.
+func closeObjects(node any) {
+	switch v := node.(type) {
+	case map[string]any:
+		if _, ok := v["properties"]; ok {
+			if _, set := v["additionalProperties"]; !set {
+				v["additionalProperties"] = false
+			}
+		}
+		for _, child := range v {
+			closeObjects(child)
+		}
+	case []any:
+		for _, child := range v {
+			closeObjects(child)
+		}
+	}
+}
+
+// collectViolations flattens the error tree into its leaves, which are the
+// actual violations; inner nodes only say that a subschema failed.
+func collectViolations(verr *jsonschema.ValidationError, p *message.Printer, out []models.Violation) []models.Violation {
+	if len(verr.Causes) > 0 {
+		for _, cause := range verr.Causes {
+			out = collectViolations(cause, p, out)
+		}
+		return out
+	}
+
+	path := jsonPointer(verr.InstanceLocation)
+	switch k := verr.ErrorKind.(type) {
+	case *kind.Required:
+		for _, name := range k.Missing {
+			out = append(out, models.Violation{Path: path + "/" + escapePointer(name), Message: "missing required property"})
+		}
+	case *kind.AdditionalProperties:
+		for _, name := range k.Properties {
+			out = append(out, models.Violation{Path: path + "/" + escapePointer(name), Message: "unknown property"})
+		}
+	case *kind.Type:
+		out = append(out, models.Violation{Path: path, Expected: strings.Join(k.Want, " or "), Message: k.LocalizedString(p)})
+	default:
+		out = append(out, models.Violation{Path: path, Message: verr.ErrorKind.LocalizedString(p)})
+	}
+	return out
+}
+
+func jsonPointer(location []string) string {
+	var b strings.Builder
+	for _, token := range location {
+		b.WriteString("/")
+		b.WriteString(escapePointer(token))
+	}
+	return b.String()
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,129 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const paymentOrderManifest = `
+schema:
+  type: object
+  required: [receiverName, amount]
+  properties:
+    receiverName: {type: string}
+    receiverResidencyAndEconomicCode1: {type: string}
+    amount: {type: number, minimum: 0}
+    payments:
+      type: array
+      items:
+        type: object
+        properties:
+          date: {type: string}
+`
+
+func TestValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "PAYMENT_ORDER.html"), "<p>{{ receiverName }}</p>")
+	writeFile(t, filepath.Join(tmpDir, "PAYMENT_ORDER.manifest.yaml"), paymentOrderManifest)
+	writeFile(t, filepath.Join(tmpDir, "FREE.html"), "<p></p>")
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	tests := []struct {
+		name   string
+		req    models.RequestBody
+		want   []models.Violation
+		strict bool
+	}{
+		{
+			name: "valid data",
+			req: models.RequestBody{Code: "PAYMENT_ORDER", Data: map[string]any{
+				"receiverName": "ТОО", "amount": 10, "typo": "ignored when lenient",
+			}},
+		},
+		{
+			name: "template without schema",
+			req:  models.RequestBody{Code: "FREE", Data: map[string]any{"anything": 1}},
+		},
+		{
+			name: "missing and mistyped fields",
+			req: models.RequestBody{Code: "PAYMENT_ORDER", Data: map[string]any{
+				"amount":   "100",
+				"payments": []any{map[string]any{"date": 20240101}},
+			}},
+			want: []models.Violation{
+				{Path: "/amount", Expected: "number"},
+				{Path: "/payments/0/date", Expected: "string"},
+				{Path: "/receiverName", Message: "missing required property"},
+			},
+		},
+		{
+			name: "strict rejects unknown properties at every level",
+			req: models.RequestBody{Code: "PAYMENT_ORDER", Strict: true, Data: map[string]any{
+				"receiverName":                      "ТОО",
+				"amount":                            1,
+				"recieverResidencyAndEconomicCode1": "17",
+				"payments":                          []any{map[string]any{"date": "x", "extra": true}},
+			}},
+			want: []models.Violation{
+				{Path: "/payments/0/extra", Message: "unknown property"},
+				{Path: "/recieverResidencyAndEconomicCode1", Message: "unknown property"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.Validate(context.Background(), &tt.req)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+
+			var verr *services.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			if !errors.Is(err, services.ErrInvalidData) {
+				t.Error("ValidationError should match ErrInvalidData")
+			}
+
+			got := make([]models.Violation, len(verr.Violations))
+			for i, v := range verr.Violations {
+				if v.Message == "" {
+					t.Errorf("violation %s has no message", v.Path)
+				}
+				got[i] = models.Violation{Path: v.Path, Expected: v.Expected}
+				// only compare fixed messages, type errors are worded by the library
+				if v.Expected == "" {
+					got[i].Message = v.Message
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateHTML_RejectsInvalidDataBeforeRendering(t *testing.T) {
+	tmpDir := t.TempDir()
+	// the template would fail to parse, so reaching the renderer fails the test
+	writeFile(t, filepath.Join(tmpDir, "PAYMENT_ORDER.html"), "{% broken")
+	writeFile(t, filepath.Join(tmpDir, "PAYMENT_ORDER.manifest.yaml"), paymentOrderManifest)
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil)
+
+	_, err := svc.GenerateHTML(context.Background(), &models.RequestBody{Code: "PAYMENT_ORDER", Format: "html", Data: map[string]any{}})
+	if !errors.Is(err, services.ErrInvalidData) {
+		t.Errorf("GenerateHTML() error = %v, want ErrInvalidData", err)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"RBKproject4/internal/renderers"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// WithStrictVariables sets whether templates that use variables missing from
+// the data fail to render. Requests can override it with strictVariables.
+func WithStrictVariables(strict bool) Option {
+	return func(s *DocumentService) {
+		s.strictVariables = strict
+	}
+}
+
+func (s *DocumentService) isStrict(override *bool) bool {
+	if override != nil {
+		return *override
+	}
+	return s.strictVariables
+}
+
+// checkVariables looks for variables that the source template of code uses
+// and dataMap does not define, and returns them as warnings. In strict mode an
+// HTML template that looks any of them up while rendering fails the request
+// with an UndefinedVariablesError. The static list alone never does, as it
+// can't see every guard; office templates are rendered elsewhere, so for them
+// strict mode only warns.
+func (s *DocumentService) checkVariables(ctx context.Context, code, source string, dataMap map[string]interface{}, strict bool) ([]string, error) {
+	var paths []string
+	var err error
+
+	checker, _ := s.templateRenderer.(renderers.VariableChecker)
+	if source == "html" {
+		if checker == nil {
+			return nil, nil
+		}
+		paths, err = checker.UndefinedVariables(code, dataMap)
+	} else {
+		var raw []byte
+		raw, err = s.templateFile(code, source)
+		if err == nil {
+			text, textErr := renderers.OfficeText(raw)
+			if textErr != nil {
+				// the renderer decides whether the file is usable at all
+				s.logger.WarnContext(ctx, "failed to read template text", "code", code, "error", textErr)
+				return nil, nil
+			}
+			paths = renderers.UndefinedVariables(text, dataMap)
+		}
+	}
+
+	// a missing template is reported by the renderer itself
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking template variables: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	if strict && source == "html" {
+		lookups, err := checker.UndefinedLookups(code, dataMap)
+		if err != nil {
+			// the render that follows reports a template that fails to render
+			s.logger.WarnContext(ctx, "failed to check template lookups", "code", code, "error", err)
+		} else if len(lookups) > 0 {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidData, &renderers.UndefinedVariablesError{Template: code, Paths: lookups})
+		}
+	}
+	s.logger.WarnContext(ctx, "template uses undefined variables", "code", code, "paths", paths)
+	return paths, nil
+}
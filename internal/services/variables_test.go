@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/renderers"
+	"RBKproject4/internal/services"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateHTML_UndefinedVariables(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "PDP.html"), "<p>{{ clientName }}, {{ clientIin }}</p>")
+
+	strict, lenient := true, false
+	tests := []struct {
+		name         string
+		global       bool
+		override     *bool
+		wantErr      bool
+		wantWarnings []string
+	}{
+		{"lenient by default", false, nil, false, []string{"clientIin"}},
+		{"strict globally", true, nil, true, nil},
+		{"strict per request", false, &strict, true, nil},
+		{"lenient per request", true, &lenient, false, []string{"clientIin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil,
+				services.WithStrictVariables(tt.global))
+
+			doc, err := svc.GenerateHTML(context.Background(), &models.RequestBody{
+				Code:            "PDP",
+				Format:          "html",
+				Data:            map[string]any{"clientName": "Aigerim"},
+				StrictVariables: tt.override,
+			})
+
+			if tt.wantErr {
+				var uerr *renderers.UndefinedVariablesError
+				if !errors.As(err, &uerr) {
+					t.Fatalf("error = %v, want UndefinedVariablesError", err)
+				}
+				if !errors.Is(err, services.ErrInvalidData) {
+					t.Errorf("error does not wrap ErrInvalidData")
+				}
+				if !reflect.DeepEqual(uerr.Paths, []string{"clientIin"}) {
+					t.Errorf("paths = %v, want [clientIin]", uerr.Paths)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", doc.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestGenerateHTML_StrictIgnoresGuardedVariables(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "PDP.html"), "<p>{% if account.iban %}{{ account.bic }}{% endif %}</p>")
+
+	svc := services.NewDocumentService(nil, renderers.NewPongo2Renderer(tmpDir), "", tmpDir, "", nil,
+		services.WithStrictVariables(true))
+
+	doc, err := svc.GenerateHTML(context.Background(), &models.RequestBody{
+		Code:   "PDP",
+		Format: "html",
+		Data:   map[string]any{"account": map[string]any{"number": "KZ01"}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateHTML() error: %v", err)
+	}
+	// the static check can't tell, so it still warns
+	if !reflect.DeepEqual(doc.Warnings, []string{"account.bic"}) {
+		t.Errorf("warnings = %v, want [account.bic]", doc.Warnings)
+	}
+}
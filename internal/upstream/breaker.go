@@ -0,0 +1,132 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while its breaker
+// is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// OpenError reports a call refused by an open breaker and when the upstream
+// will be tried again.
+type OpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: %s, retry in %s", ErrCircuitOpen, e.Upstream, e.RetryAfter.Round(time.Second))
+}
+
+func (e *OpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// State is the state of a breaker.
+type State int
+
+const (
+	// StateClosed lets every call through.
+	StateClosed State = iota
+	// StateOpen refuses calls until the open duration has passed.
+	StateOpen
+	// StateHalfOpen lets one trial call through to decide whether to close.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker stops calls to an upstream after a run of consecutive failures, so
+// that requests fail fast instead of each waiting for a dead upstream.
+type Breaker struct {
+	threshold int
+	openFor   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewBreaker opens after threshold consecutive failures and stays open for
+// openFor before letting a trial call through.
+func NewBreaker(threshold int, openFor time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, openFor: openFor, now: time.Now}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow reports whether a call may go through and, if not, how long until
+// the next trial.
+func (b *Breaker) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return false, b.openedAt.Add(b.openFor).Sub(b.now())
+	case StateHalfOpen:
+		if b.trial {
+			// another call is already finding out
+			return false, b.openFor
+		}
+		b.trial = true
+	}
+	return true, 0
+}
+
+// Record reports the outcome of a call that Allow let through.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if success {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release ends a call that Allow let through without an outcome, e.g. one
+// its caller cancelled, so that it counts neither way.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// advance moves an open breaker whose time is up to half-open.
+func (b *Breaker) advance() {
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.openFor)) {
+		b.state = StateHalfOpen
+	}
+}
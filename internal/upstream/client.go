@@ -0,0 +1,216 @@
+// Package upstream calls the services documents are rendered by, with a
+// timeout per attempt, bounded retries and a circuit breaker per upstream.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "RBKproject4/internal/upstream"
+
+// Options configure a Client. The zero value makes a single attempt without a
+// timeout of its own and never opens a breaker.
+type Options struct {
+	// Timeout limits each attempt.
+	Timeout time.Duration
+	// MaxAttempts bounds the attempts per call, the first one included.
+	MaxAttempts int
+	// Backoff is the base wait before the second attempt; it doubles for
+	// every later one and is jittered.
+	Backoff time.Duration
+	// FailureThreshold is the number of consecutive failed attempts that
+	// opens the breaker.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open.
+	OpenDuration time.Duration
+}
+
+// Client sends requests to one upstream.
+type Client struct {
+	name    string
+	client  *http.Client
+	opts    Options
+	breaker *Breaker
+}
+
+// New makes a client for the upstream called name.
+func New(name string, client *http.Client, opts Options) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	c := &Client{name: name, client: client, opts: opts}
+	if opts.FailureThreshold > 0 {
+		c.breaker = NewBreaker(opts.FailureThreshold, opts.OpenDuration)
+	}
+	return c
+}
+
+// Name returns the name of the upstream.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// State returns the state of the upstream's breaker.
+func (c *Client) State() State {
+	if c.breaker == nil {
+		return StateClosed
+	}
+	return c.breaker.State()
+}
+
+// Do sends req, again after a connection error or a 502 or 503 as long as
+// attempts are left. A request with a body must be replayable, which it is
+// when made by http.NewRequest from a bytes.Buffer, bytes.Reader or
+// strings.Reader. The response body must be closed.
+//
+// Each call is traced as a client span, whose context is passed on in the
+// traceparent header.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), c.name+" "+req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(req, attempt)
+		if !retryable(ctx, resp, err) || attempt >= c.opts.MaxAttempts {
+			recordOutcome(span, attempt, resp, err)
+			return resp, err
+		}
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(c.backoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	if c.breaker != nil {
+		if ok, retryAfter := c.breaker.Allow(); !ok {
+			return nil, &OpenError{Upstream: c.name, RetryAfter: retryAfter}
+		}
+	}
+
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if c.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+	}
+	attemptReq := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("error replaying request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := c.client.Do(attemptReq)
+	switch {
+	case c.breaker == nil:
+	case req.Context().Err() != nil:
+		// the caller gave up, which says nothing about the upstream
+		c.breaker.Release()
+	default:
+		c.breaker.Record(err == nil && !unavailable(resp.StatusCode))
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable reports whether an attempt failed in a way another attempt may
+// not: the connection failed, or the upstream is restarting or overloaded.
+// Attempts that timed out are not repeated, they would most likely time out
+// again.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		var oerr *OpenError
+		return !errors.As(err, &oerr) && !errors.Is(err, context.DeadlineExceeded) && !isTimeout(err)
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// unavailable reports whether a status says the upstream itself is down,
+// rather than that it failed on this one request: only those count against
+// the breaker, so that requests that can't be rendered don't open it for
+// everyone.
+func unavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+func isTimeout(err error) bool {
+	var terr interface{ Timeout() bool }
+	return errors.As(err, &terr) && terr.Timeout()
+}
+
+// backoff is the wait after the given attempt: the base doubled per attempt,
+// with the upper half jittered so that clients don't retry in step.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.Backoff << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// recordOutcome sets the result of a call on its span.
+func recordOutcome(span trace.Span, attempts int, resp *http.Response, err error) {
+	span.SetAttributes(attribute.Int("upstream.attempts", attempts))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+}
+
+// cancelBody ends the attempt's timeout once the body has been read.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,203 @@
+package upstream_test
+
+import (
+	"RBKproject4/internal/upstream"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky answers with the given statuses in turn, then with 200, and checks
+// that every attempt carries the whole body.
+func flaky(t *testing.T, calls *atomic.Int32, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+			t.Errorf("attempt %d body = %q", n, body)
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+}
+
+func post(t *testing.T, c *upstream.Client, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		attempts  int32
+		wantCalls int32
+		want      int
+	}{
+		{"recovers", []int{http.StatusServiceUnavailable, http.StatusBadGateway}, 3, 3, http.StatusOK},
+		{"gives up", []int{503, 503, 503, 503}, 3, 3, http.StatusServiceUnavailable},
+		{"client error", []int{http.StatusBadRequest}, 3, 1, http.StatusBadRequest},
+		{"server error", []int{http.StatusInternalServerError}, 3, 1, http.StatusInternalServerError},
+		{"single attempt", []int{http.StatusServiceUnavailable}, 1, 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := flaky(t, &calls, tt.statuses...)
+			defer srv.Close()
+
+			c := upstream.New("test", srv.Client(), upstream.Options{MaxAttempts: int(tt.attempts), Backoff: time.Millisecond})
+			resp, err := post(t, c, srv.URL)
+			if err != nil {
+				t.Fatalf("Do() error: %v", err)
+			}
+			if resp.StatusCode != tt.want || calls.Load() != tt.wantCalls {
+				t.Errorf("status = %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.want, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_RetriesConnectionErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	start := time.Now()
+	c := upstream.New("test", srv.Client(), upstream.Options{MaxAttempts: 3, Backoff: 20 * time.Millisecond})
+	if _, err := post(t, c, srv.URL); err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	// two waits of at least half the backoff, 20ms then 40ms
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("attempts were not backed off: %s", elapsed)
+	}
+}
+
+func TestClient_TimeoutNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := upstream.New("test", srv.Client(), upstream.Options{Timeout: 10 * time.Millisecond, MaxAttempts: 3})
+	_, err := post(t, c, srv.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want a deadline error", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestClient_Breaker(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	c := upstream.New("gotenberg", srv.Client(), upstream.Options{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond})
+	post(t, c, srv.URL)
+	post(t, c, srv.URL)
+	if c.State() != upstream.StateOpen {
+		t.Fatalf("state = %s after 2 failures, want open", c.State())
+	}
+
+	_, err := post(t, c, srv.URL)
+	var oerr *upstream.OpenError
+	if !errors.As(err, &oerr) || !errors.Is(err, upstream.ErrCircuitOpen) {
+		t.Fatalf("error = %v, want OpenError", err)
+	}
+	if oerr.Upstream != "gotenberg" || oerr.RetryAfter <= 0 || oerr.RetryAfter > 50*time.Millisecond {
+		t.Errorf("OpenError = %+v", oerr)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2: open breaker let a call through", calls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if c.State() != upstream.StateHalfOpen {
+		t.Fatalf("state = %s after the open duration, want half-open", c.State())
+	}
+	failing.Store(false)
+	if resp, err := post(t, c, srv.URL); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("trial call: %v", err)
+	}
+	if c.State() != upstream.StateClosed {
+		t.Errorf("state = %s after a successful trial, want closed", c.State())
+	}
+}
+
+func TestClient_BreakerIgnoresCancelledCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := upstream.New("gotenberg", srv.Client(), upstream.Options{FailureThreshold: 2, OpenDuration: time.Minute})
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, bytes.NewBufferString("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Do(req); err == nil {
+			t.Fatal("want an error for a cancelled call")
+		}
+		cancel()
+	}
+	if c.State() != upstream.StateClosed {
+		t.Errorf("state = %s after cancelled calls, want closed", c.State())
+	}
+}
+
+func TestClient_BreakerIgnoresRequestErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		// as the Python service answers a template it couldn't render
+		if calls.Load()%2 == 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := upstream.New("python", srv.Client(), upstream.Options{FailureThreshold: 2, OpenDuration: time.Minute})
+	for i := 0; i < 6; i++ {
+		if _, err := post(t, c, srv.URL); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if c.State() != upstream.StateClosed {
+		t.Errorf("state = %s after render errors, want closed", c.State())
+	}
+	if calls.Load() != 6 {
+		t.Errorf("calls = %d, want 6", calls.Load())
+	}
+}
@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrHostNotAllowed reports a callback URL the service refuses to call.
+var ErrHostNotAllowed = errors.New("callback host not allowed")
+
+// HostPolicy limits where callbacks go, so that a callbackUrl can't be used
+// to make the service call its own network.
+type HostPolicy struct {
+	// Allowed are patterns (path.Match) of the hosts callbacks may go to.
+	// Empty allows any host.
+	Allowed []string
+	// Private allows loopback, private and link-local addresses, which are
+	// refused otherwise.
+	Private bool
+}
+
+// Check reports whether a callback may be sent to rawURL. Host names are
+// only resolved when the callback is sent, by the client of Client.
+func (p HostPolicy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: %q is not an absolute http(s) URL", ErrHostNotAllowed, rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if len(p.Allowed) > 0 {
+		allowed := false
+		for _, pattern := range p.Allowed {
+			if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+		}
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !p.Private && !public(ip) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	return nil
+}
+
+// Client returns an HTTP client for callbacks that refuses to connect to
+// addresses the policy doesn't allow, whatever the host name resolved to and
+// however many redirects led there.
+func (p HostPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrHostNotAllowed, address)
+			}
+			if !p.Private && !public(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrHostNotAllowed, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would connect on our behalf, past the check above
+	transport.Proxy = nil
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// public reports whether ip is a unicast address outside our own networks.
+func public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
@@ -0,0 +1,60 @@
+package webhooks_test
+
+import (
+	"RBKproject4/internal/webhooks"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHostPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy webhooks.HostPolicy
+		url    string
+		ok     bool
+	}{
+		{"public host", webhooks.HostPolicy{}, "https://hooks.example.kz/done", true},
+		{"public address", webhooks.HostPolicy{}, "http://93.184.216.34:8080/", true},
+		{"scheme", webhooks.HostPolicy{}, "ftp://hooks.example.kz/", false},
+		{"relative", webhooks.HostPolicy{}, "/jobs/done", false},
+		{"loopback", webhooks.HostPolicy{}, "http://127.0.0.1:8080/", false},
+		{"private", webhooks.HostPolicy{}, "http://10.1.2.3/", false},
+		{"metadata", webhooks.HostPolicy{}, "http://169.254.169.254/latest/meta-data", false},
+		{"ipv6 loopback", webhooks.HostPolicy{}, "http://[::1]/", false},
+		{"mapped loopback", webhooks.HostPolicy{}, "http://[::ffff:127.0.0.1]/", false},
+		{"private allowed", webhooks.HostPolicy{Private: true}, "http://10.1.2.3/", true},
+		{"listed", webhooks.HostPolicy{Allowed: []string{"*.bank.kz"}}, "https://crm.bank.kz/hook", true},
+		{"not listed", webhooks.HostPolicy{Allowed: []string{"*.bank.kz"}}, "https://evil.example.kz/hook", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.url)
+			if tt.ok && err != nil {
+				t.Errorf("Check(%q) error: %v", tt.url, err)
+			}
+			if !tt.ok && !errors.Is(err, webhooks.ErrHostNotAllowed) {
+				t.Errorf("Check(%q) error = %v, want ErrHostNotAllowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestHostPolicy_ClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// the address is checked again when connecting, which covers host names
+	// that resolve to it
+	if _, err := (webhooks.HostPolicy{}).Client(time.Second).Get(srv.URL); !errors.Is(err, webhooks.ErrHostNotAllowed) {
+		t.Errorf("Get() error = %v, want ErrHostNotAllowed", err)
+	}
+
+	resp, err := (webhooks.HostPolicy{Private: true}).Client(time.Second).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() with private addresses allowed error: %v", err)
+	}
+	resp.Body.Close()
+}
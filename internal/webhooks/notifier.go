@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"RBKproject4/internal/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Signature-Timestamp"
+)
+
+var ErrNoSecret = errors.New("no webhook secret configured for client")
+
+// Notifier POSTs signed job completion callbacks, retrying with exponential
+// backoff until the receiver answers 2xx or the attempts run out.
+type Notifier struct {
+	client      *http.Client
+	secrets     map[string]string
+	maxAttempts int
+	backoff     time.Duration
+	logger      *slog.Logger
+}
+
+func NewNotifier(logger *slog.Logger, client *http.Client, secrets map[string]string, maxAttempts int, backoff time.Duration) *Notifier {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Notifier{
+		client:      client,
+		secrets:     secrets,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		logger:      logger,
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers should
+// recompute it with their secret and compare in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver sends payload to callbackURL on behalf of clientID. Every attempt is
+// passed to record as it happens so the caller can persist the delivery
+// history. A callback the host policy refuses to connect to is not retried.
+func (n *Notifier) Deliver(ctx context.Context, callbackURL, clientID string, payload *models.WebhookPayload, record func(models.WebhookDelivery)) error {
+	secret, ok := n.secrets[clientID]
+	if !ok || secret == "" {
+		err := fmt.Errorf("%w %q", ErrNoSecret, clientID)
+		record(models.WebhookDelivery{Attempt: 1, At: time.Now().UTC(), Error: err.Error()})
+		return err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	// the path and query of a callback URL may carry the receiver's tokens
+	target := redact(callbackURL)
+
+	var lastErr error
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		if attempt > 1 {
+			delay := n.backoff << (attempt - 2)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		delivery, retry, err := n.send(ctx, callbackURL, secret, body)
+		delivery.Attempt = attempt
+		record(delivery)
+
+		if err == nil {
+			n.logger.Info("webhook delivered", "job_id", payload.JobID, "target", target, "attempt", attempt)
+			return nil
+		}
+
+		lastErr = err
+		n.logger.Warn("webhook delivery failed", "job_id", payload.JobID, "target", target, "attempt", attempt, "error", err)
+		if !retry {
+			break
+		}
+	}
+	return fmt.Errorf("webhook delivery failed: %w", lastErr)
+}
+
+// send makes one attempt and reports whether a failed one is worth retrying.
+func (n *Notifier) send(ctx context.Context, callbackURL, secret string, body []byte) (models.WebhookDelivery, bool, error) {
+	start := time.Now()
+	delivery := models.WebhookDelivery{At: start.UTC()}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		err = fmt.Errorf("failed to create request: %w", unwrapURL(err))
+		delivery.Error = err.Error()
+		return delivery, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		err = fmt.Errorf("failed to send request: %w", unwrapURL(err))
+		delivery.Error = err.Error()
+		// the policy won't allow the address on any later attempt either
+		return delivery, ctx.Err() == nil && !errors.Is(err, ErrHostNotAllowed), err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			n.logger.Warn("failed to close response body", "err", err)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("receiver returned status %d", resp.StatusCode)
+		delivery.Error = err.Error()
+		// other 4xx answers mean the receiver rejected the callback for good
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+		return delivery, retry, err
+	}
+	return delivery, false, nil
+}
+
+// unwrapURL drops the method and URL that *url.Error puts in front of the
+// cause.
+func unwrapURL(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
+
+// redact leaves only the scheme and host of a callback URL, for logs.
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,152 @@
+package webhooks_test
+
+import (
+	"RBKproject4/internal/models"
+	"RBKproject4/internal/webhooks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newNotifier(secrets map[string]string, attempts int) *webhooks.Notifier {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return webhooks.NewNotifier(logger, http.DefaultClient, secrets, attempts, time.Millisecond)
+}
+
+func TestNotifier_DeliversSignedPayload(t *testing.T) {
+	var gotBody []byte
+	var gotSig, gotTS string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get(webhooks.SignatureHeader)
+		gotTS = r.Header.Get(webhooks.TimestampHeader)
+	}))
+	defer srv.Close()
+
+	n := newNotifier(map[string]string{"bank": "s3cret"}, 3)
+
+	var deliveries []models.WebhookDelivery
+	payload := &models.WebhookPayload{JobID: "j1", Status: models.JobSucceeded, Checksum: "abc"}
+	err := n.Deliver(context.Background(), srv.URL, "bank", payload, func(d models.WebhookDelivery) {
+		deliveries = append(deliveries, d)
+	})
+	if err != nil {
+		t.Fatalf("Deliver() error: %v", err)
+	}
+
+	if len(deliveries) != 1 || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("deliveries = %+v, want one successful attempt", deliveries)
+	}
+
+	want := "sha256=" + webhooks.Sign("s3cret", gotTS, gotBody)
+	if gotSig != want {
+		t.Errorf("signature = %q, want %q", gotSig, want)
+	}
+
+	var got models.WebhookPayload
+	if err := json.Unmarshal(gotBody, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.JobID != "j1" || got.Checksum != "abc" {
+		t.Errorf("payload = %+v", got)
+	}
+}
+
+func TestNotifier_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	n := newNotifier(map[string]string{"bank": "s3cret"}, 5)
+
+	var deliveries []models.WebhookDelivery
+	err := n.Deliver(context.Background(), srv.URL, "bank", &models.WebhookPayload{JobID: "j1"}, func(d models.WebhookDelivery) {
+		deliveries = append(deliveries, d)
+	})
+	if err != nil {
+		t.Fatalf("Deliver() error: %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(deliveries))
+	}
+	if deliveries[0].StatusCode != http.StatusServiceUnavailable || deliveries[0].Error == "" {
+		t.Errorf("first attempt = %+v, want a recorded 503", deliveries[0])
+	}
+}
+
+func TestNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	n := newNotifier(map[string]string{"bank": "s3cret"}, 5)
+
+	err := n.Deliver(context.Background(), srv.URL, "bank", &models.WebhookPayload{JobID: "j1"}, func(models.WebhookDelivery) {})
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Deliver() error = %v, want a 400 failure", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestNotifier_UnknownClient(t *testing.T) {
+	n := newNotifier(map[string]string{"bank": "s3cret"}, 3)
+
+	var deliveries []models.WebhookDelivery
+	err := n.Deliver(context.Background(), "http://127.0.0.1:1", "other", &models.WebhookPayload{JobID: "j1"}, func(d models.WebhookDelivery) {
+		deliveries = append(deliveries, d)
+	})
+	if !errors.Is(err, webhooks.ErrNoSecret) {
+		t.Errorf("Deliver() error = %v, want ErrNoSecret", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Error == "" {
+		t.Errorf("expected the failure to be recorded, got %+v", deliveries)
+	}
+}
+
+func TestNotifier_DoesNotRetryRefusedHosts(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	// the test server listens on loopback, which the policy refuses
+	n := webhooks.NewNotifier(logger, webhooks.HostPolicy{}.Client(time.Second), map[string]string{"bank": "s3cret"}, 5, time.Millisecond)
+
+	var deliveries []models.WebhookDelivery
+	err := n.Deliver(context.Background(), srv.URL+"/hooks?token=t0ps3cret", "bank", &models.WebhookPayload{JobID: "j1"}, func(d models.WebhookDelivery) {
+		deliveries = append(deliveries, d)
+	})
+	if !errors.Is(err, webhooks.ErrHostNotAllowed) {
+		t.Errorf("Deliver() error = %v, want ErrHostNotAllowed", err)
+	}
+	if len(deliveries) != 1 || calls.Load() != 0 {
+		t.Errorf("%d attempts reached the receiver %d times, want one refused attempt", len(deliveries), calls.Load())
+	}
+	if strings.Contains(logs.String(), "t0ps3cret") || strings.Contains(logs.String(), "/hooks") {
+		t.Errorf("logs hold the callback path: %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), srv.URL) {
+		t.Errorf("logs don't name the callback host: %s", logs.String())
+	}
+}
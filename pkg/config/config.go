@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -12,6 +14,56 @@ type Config struct {
 	StaticToken       string `envconfig:"STATIC_TOKEN" default:"default_token"`
 	ServiceContextURL string `envconfig:"SERVICE_CONTEXT_URL" default:"/document-generator"`
 	TemplateDir       string `envconfig:"TEMPLATE_DIR" default:"./templates"`
+	BatchParallelism  int    `envconfig:"BATCH_PARALLELISM" default:"4"`
+	BatchMaxItems     int    `envconfig:"BATCH_MAX_ITEMS" default:"500"`
+	ComposeMaxParts   int    `envconfig:"COMPOSE_MAX_PARTS" default:"50"`
+	JobWorkers        int    `envconfig:"JOB_WORKERS" default:"4"`
+	JobQueueSize      int    `envconfig:"JOB_QUEUE_SIZE" default:"100"`
+	JobStore          string `envconfig:"JOB_STORE" default:"memory"`
+	JobStoreDir       string `envconfig:"JOB_STORE_DIR" default:"./data/jobs"`
+	StrictVariables   bool   `envconfig:"STRICT_VARIABLES" default:"false"`
+	AuditSink         string `envconfig:"AUDIT_SINK" default:"file"`
+	AuditFile         string `envconfig:"AUDIT_FILE" default:"./data/audit.jsonl"`
+
+	// ClientsFile replaces StaticToken with a registry of clients when set
+	ClientsFile string `envconfig:"CLIENTS_FILE"`
+
+	PythonTimeout           time.Duration `envconfig:"PYTHON_TIMEOUT" default:"15s"`
+	GotenbergTimeout        time.Duration `envconfig:"GOTENBERG_TIMEOUT" default:"15s"`
+	BatchTimeout            time.Duration `envconfig:"BATCH_TIMEOUT" default:"10m"`
+	ComposeTimeout          time.Duration `envconfig:"COMPOSE_TIMEOUT" default:"5m"`
+	JobRetention            time.Duration `envconfig:"JOB_RETENTION" default:"24h"`
+	JobCleanupInterval      time.Duration `envconfig:"JOB_CLEANUP_INTERVAL" default:"10m"`
+	UpstreamMaxAttempts     int           `envconfig:"UPSTREAM_MAX_ATTEMPTS" default:"3"`
+	UpstreamBackoff         time.Duration `envconfig:"UPSTREAM_BACKOFF" default:"200ms"`
+	BreakerFailureThreshold int           `envconfig:"BREAKER_FAILURE_THRESHOLD" default:"5"`
+	BreakerOpenDuration     time.Duration `envconfig:"BREAKER_OPEN_DURATION" default:"30s"`
+
+	// values of these fields, and matches of these patterns, are masked in
+	// logs; see logging.RedactRules
+	LogRedactFields   []string `envconfig:"LOG_REDACT_FIELDS" default:"*iin,*bin,*iban,*accountnumber,*cardnumber,*pan,*fullname*,*clientname,name,surname,firstname,lastname,middlename,patronymic,*email,*phone*"`
+	LogRedactPatterns []string `envconfig:"LOG_REDACT_PATTERNS" default:"iin,iban,pan,email"`
+
+	TracingExporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	TracingEndpoint    string  `envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+	TracingInsecure    bool    `envconfig:"TRACING_OTLP_INSECURE" default:"false"`
+	TracingServiceName string  `envconfig:"TRACING_SERVICE_NAME" default:"document-generator"`
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	ReadinessCacheTTL  time.Duration `envconfig:"READINESS_CACHE_TTL" default:"5s"`
+	ReadinessTimeout   time.Duration `envconfig:"READINESS_TIMEOUT" default:"2s"`
+	ShutdownDrainDelay time.Duration `envconfig:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	PublicBaseURL      string            `envconfig:"PUBLIC_BASE_URL" default:"http://localhost:8080"`
+	WebhookSecrets     map[string]string `envconfig:"WEBHOOK_SECRETS"`
+	WebhookMaxAttempts int               `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"5"`
+	WebhookBackoff     time.Duration     `envconfig:"WEBHOOK_BACKOFF" default:"1s"`
+	WebhookTimeout     time.Duration     `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+
+	// callbacks only go to these hosts, if set, and never to private
+	// addresses unless WebhookAllowPrivate; see webhooks.HostPolicy
+	WebhookAllowedHosts []string `envconfig:"WEBHOOK_ALLOWED_HOSTS"`
+	WebhookAllowPrivate bool     `envconfig:"WEBHOOK_ALLOW_PRIVATE" default:"false"`
 }
 
 func Load() (*Config, error) {
@@ -1,7 +1,27 @@
-from fastapi import FastAPI
+import logging
+
+from fastapi import FastAPI, Request
 from app.routes import docx, xlsx
 
+logger = logging.getLogger("uvicorn.error")
+
 app = FastAPI(title="Template Renderer")
 
 app.include_router(docx.router)
-app.include_router(xlsx.router)
\ No newline at end of file
+app.include_router(xlsx.router)
+
+
+@app.middleware("http")
+async def request_id(request: Request, call_next):
+    # the Go service sends the ID of the request a render belongs to
+    rid = request.headers.get("X-Request-ID")
+    response = await call_next(request)
+    if rid:
+        response.headers["X-Request-ID"] = rid
+        logger.info("%s %s %d request_id=%s", request.method, request.url.path, response.status_code, rid)
+    return response
+
+
+@app.get("/health")
+def health():
+    return {"status": "ok"}
@@ -1,16 +1,26 @@
+import json
+import logging
+
 from fastapi import APIRouter, UploadFile, File, Form
-from fastapi.responses import FileResponse
+from fastapi.responses import FileResponse, JSONResponse
 from app.services.docx_service import render_docx_file
 
+logger = logging.getLogger(__name__)
+
 router = APIRouter()
 
 @router.post("/docx/render")
 async def render_docx(template: UploadFile = File(...), data: str = Form(...)):
-    import json
-    data_dict = json.loads(data)  
-    output_path = render_docx_file(template.file, data_dict)
+    # a template or data that can't be rendered is the request's fault, not
+    # the service's: answering 500 would count it against the caller's breaker
+    try:
+        data_dict = json.loads(data)
+        output_path = render_docx_file(template.file, data_dict)
+    except Exception as exc:
+        logger.warning("docx render failed: %s", exc)
+        return JSONResponse(status_code=422, content={"detail": f"docx render failed: {exc}"})
     return FileResponse(
         output_path,
         media_type="application/vnd.openxmlformats-officedocument.wordprocessingml.document",
         filename="output.docx"
-    )
\ No newline at end of file
+    )
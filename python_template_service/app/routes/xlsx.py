@@ -1,15 +1,24 @@
 # FastAPI Router
+import json
+import logging
+
 from fastapi import APIRouter, UploadFile, File, Form
-from fastapi.responses import FileResponse
+from fastapi.responses import FileResponse, JSONResponse
 from app.services.xlsx_service import render_xlsx_file
 
+logger = logging.getLogger(__name__)
+
 router = APIRouter()
 
 @router.post("/xlsx/render")
 async def render_xlsx(template: UploadFile = File(...), data: str = Form(...)):
-    import json
-    data_dict = json.loads(data)  
-    output_path = render_xlsx_file(template.file, data_dict)
+    # see render_docx: render errors are answered with 422, not 500
+    try:
+        data_dict = json.loads(data)
+        output_path = render_xlsx_file(template.file, data_dict)
+    except Exception as exc:
+        logger.warning("xlsx render failed: %s", exc)
+        return JSONResponse(status_code=422, content={"detail": f"xlsx render failed: {exc}"})
     return FileResponse(
         output_path,
         media_type="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",